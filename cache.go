@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/autumnkelsey/gorganize/formatters"
+)
+
+// A record of files known to be formatted, so repeat runs with --cache can skip them.
+// Each entry is an empty file named by the hash of a formatted file's path, contents, and config, salted with
+// the gorganize executable; rebuilding gorganize or changing any option invalidates every entry.
+// The cache is best effort: failing to read or write an entry just means the file is formatted again.
+type formatCache struct {
+	dir  string
+	salt []byte
+}
+
+// Record that src is the content of the file at path formatted with config.
+func (c *formatCache) add(config formatters.Config, path string, src []byte) {
+	if c == nil {
+		return
+	}
+	entry := c.entry(config, path, src)
+	if err := os.MkdirAll(filepath.Dir(entry), 0o755); err == nil {
+		_ = os.WriteFile(entry, nil, 0o644)
+	}
+}
+
+// Return the path of the entry for src at path, sharded by the first byte of its hash.
+func (c *formatCache) entry(config formatters.Config, path string, src []byte) string {
+	h := sha256.New()
+	h.Write(c.salt)
+	writeField(h, fmt.Appendf(nil, "%#v", config))
+	writeField(h, []byte(path))
+	writeField(h, src)
+	key := hex.EncodeToString(h.Sum(nil))
+	return filepath.Join(c.dir, key[:2], key[2:])
+}
+
+// Report whether src is known to be the content of the file at path formatted with config.
+func (c *formatCache) has(config formatters.Config, path string, src []byte) bool {
+	if c == nil {
+		return false
+	}
+	_, err := os.Stat(c.entry(config, path, src))
+	return err == nil
+}
+
+// Open the cache under the user's cache directory, e.g. $XDG_CACHE_HOME/gorganize.
+func newFormatCache() (*formatCache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(exe)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return &formatCache{filepath.Join(dir, "gorganize"), h.Sum(nil)}, nil
+}
+
+// Write b prefixed with its length, so consecutive fields can't run together.
+func writeField(h hash.Hash, b []byte) {
+	fmt.Fprintf(h, "%d:", len(b))
+	h.Write(b)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/autumnkelsey/gorganize/formatters"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// The order aifi sorts top-level declarations in, for the config command.
+const declOrder = "imports, constants, variables, types, functions"
+
+func newConfigCommand(flags *pflag.FlagSet) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config [flags] [file ...]",
+		Short: "Print the settings each file would be formatted with",
+		Long: "Prints the settings gorganize would format each file with, after applying the defaults, " +
+			".editorconfig files, flags, and the file's //gorganize: directives, in increasing precedence. " +
+			"Without files, prints the settings for a new .go file in the current directory.",
+		Args: cobra.ArbitraryArgs,
+		RunE: printConfig,
+	}
+	cmd.Flags().AddFlagSet(flags)
+	return cmd
+}
+
+func printConfig(cmd *cobra.Command, args []string) error {
+	if err := resolveConfig(cmd); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		args = []string{"new.go"} // only its directory matters, for .editorconfig
+	}
+
+	out := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	for i, path := range args {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		if err := printFileConfig(out, path); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// Print the settings for the file at path, which need not exist; a file that does is checked for directives.
+func printFileConfig(w *tabwriter.Writer, path string) error {
+	config, err := fileConfig(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	testdata := false
+	if src, err := os.ReadFile(path); err == nil {
+		if config, testdata, err = formatters.FileConfig(config, src); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	abs, _ := filepath.Abs(path)
+	fmt.Fprintf(w, "file:\t%s\n", abs)
+	if testdata {
+		fmt.Fprintln(w, "testdata:\ttrue (left unchanged)")
+		return nil
+	}
+	stages := formatters.Stages(config)
+	order := declOrder
+	if !slices.Contains(stages, "aifi") {
+		order = "source order"
+	}
+	fmt.Fprintf(w, "stages:\t%s\n", strings.Join(stages, ", "))
+	fmt.Fprintf(w, "declaration order:\t%s\n", order)
+	fmt.Fprintf(w, "reorder:\t%s\n", strings.Join(config.Reorder, ", "))
+	fmt.Fprintf(w, "sort-funcs:\t%s\n", config.SortFuncs)
+	fmt.Fprintf(w, "methods:\t%s\n", config.Methods)
+	fmt.Fprintf(w, "priority-methods:\t%s\n", strings.Join(config.PriorityMethods, ", "))
+	fmt.Fprintf(w, "exported-first:\t%t\n", config.ExportedFirst)
+	fmt.Fprintf(w, "no-main-first:\t%t\n", config.NoMainFirst)
+	fmt.Fprintf(w, "fold-case:\t%t\n", config.FoldCase)
+	fmt.Fprintf(w, "group-aliases:\t%t\n", config.GroupAliases)
+	fmt.Fprintf(w, "test-helpers-first:\t%t\n", config.TestHelpersFirst)
+	fmt.Fprintf(w, "go-version:\t%s\n", lo.Ternary(config.GoVersion == "", "any", config.GoVersion))
+	fmt.Fprintf(w, "max-len:\t%d\n", config.MaxLen)
+	fmt.Fprintf(w, "tab-len:\t%d\n", config.TabLen)
+	fmt.Fprintf(w, "chain-split-dots:\t%t\n", config.ChainSplitDots)
+	fmt.Fprintf(w, "reformat-tags:\t%t\n", config.ReformatTags)
+	fmt.Fprintf(w, "shorten-comments:\t%t\n", config.ShortenComments)
+	fmt.Fprintf(w, "group-spacing:\t%d\n", config.GroupSpacing)
+	fmt.Fprintf(w, "line-ending:\t%s\n", config.LineEnding)
+	fmt.Fprintf(w, "local-prefixes:\t%s\n", strings.Join(config.LocalPrefixes, ", "))
+	fmt.Fprintf(w, "normalize-header:\t%t\n", config.NormalizeHeader)
+	return nil
+}
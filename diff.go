@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+)
+
+// Colors of the lines of a diff with --diff-color, by the first character of the line.
+// The "---" and "+++" header lines come first and are bold instead.
+var (
+	diffColors = map[byte]*color.Color{
+		'+': color.New(color.FgGreen),
+		'-': color.New(color.FgRed),
+		'@': color.New(color.FgCyan),
+	}
+	diffHeaderColor = color.New(color.Bold)
+)
+
+// Write a unified diff from the original source of the file at path to its formatted output, like gofmt -d.
+// Lines are colored with --diff-color, unless color is disabled or standard output isn't a terminal,
+// so piped output is a plain diff.
+func writeDiff(w io.Writer, path string, input, output []byte) error {
+	edits := myers.ComputeEdits(span.URIFromPath(path), string(input), string(output))
+	diff := fmt.Sprint(gotextdiff.ToUnified(path+".orig", path, string(input), edits))
+	if !diffColor || color.NoColor {
+		_, err := io.WriteString(w, diff)
+		return err
+	}
+
+	var b strings.Builder
+	for i, line := range strings.SplitAfter(diff, "\n") {
+		text := strings.TrimSuffix(line, "\n")
+		c := diffHeaderColor
+		if i >= 2 && text != "" {
+			c = diffColors[text[0]]
+		}
+		if c == nil || text == "" {
+			b.WriteString(line) // context lines, and the empty string after the last newline
+		} else {
+			b.WriteString(c.Sprint(text) + line[len(text):])
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
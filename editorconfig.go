@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/autumnkelsey/gorganize/formatters"
+	"github.com/editorconfig/editorconfig-core-go/v2"
+)
+
+// Looks up the .editorconfig files from a file's directory upwards, stopping at one with root = true.
+var editorConfig = editorconfig.Config{Parser: editorconfig.NewCachedParser()}
+
+// Return the config for formatting the file at path: the shared config, with max_line_length and indent_size
+// (or tab_width) from the .editorconfig files that apply to the file in place of the defaults.
+// --max-len takes precedence over max_line_length.
+func fileConfig(path string) (formatters.Config, error) {
+	def, _, err := editorConfig.LoadGraceful(path) // the warnings are about malformed settings, which are left unset
+	if err != nil {
+		return formatters.Config{}, err
+	}
+
+	res := config
+	if maxLen, err := strconv.Atoi(def.Raw["max_line_length"]); err == nil && maxLen > 0 && !maxLenSet {
+		res.MaxLen = maxLen
+	}
+	if def.TabWidth > 0 {
+		res.TabLen = def.TabWidth // defaults to indent_size
+	}
+	return res, nil
+}
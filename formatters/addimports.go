@@ -0,0 +1,69 @@
+package formatters
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strconv"
+
+	"github.com/samber/lo"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
+)
+
+// addImportsFormatter adds imports for the packages a file references but doesn't import, as goimports does.
+// Packages are resolved in the context of the module containing filename, searching the standard library,
+// the module's dependencies, and the module cache, so it needs a populated module cache and can be slow.
+//
+// goimports also removes unused imports; those are put back, leaving removal to unusedImportsFormatter.
+type addImportsFormatter struct{}
+
+func (addImportsFormatter) Format(filename string, src []byte) ([]byte, error) {
+	out, err := imports.Process(filename, src, &imports.Options{Comments: true, TabIndent: true, TabWidth: 8})
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	before, err := parser.ParseFile(fset, filename, src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	after, err := parser.ParseFile(fset, filename, out, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := lo.SliceToMap(after.Imports, func(spec *ast.ImportSpec) (string, bool) { return importKey(spec), true })
+	restored := false
+	for _, spec := range before.Imports {
+		if !kept[importKey(spec)] {
+			importPath, _ := strconv.Unquote(spec.Path.Value)
+			name := lo.TernaryF(spec.Name == nil, func() string { return "" }, func() string { return spec.Name.Name })
+			restored = astutil.AddNamedImport(fset, after, name, importPath) || restored
+		}
+	}
+	if !restored {
+		return out, nil
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, after); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (addImportsFormatter) Name() string {
+	return "add-imports"
+}
+
+// Identify an import by its explicit name, if any, and path.
+func importKey(spec *ast.ImportSpec) string {
+	if spec.Name == nil {
+		return spec.Path.Value
+	}
+	return spec.Name.Name + " " + spec.Path.Value
+}
@@ -7,21 +7,27 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"math"
 	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/samber/lo"
 )
 
 const (
+	COMMENT    token.Token = token.COMMENT // a free-floating comment block, which stays at the top of its group
 	CONST      token.Token = token.CONST
 	FUNC       token.Token = token.FUNC
 	IMPORT     token.Token = token.IMPORT
 	METHOD     token.Token = token.Token(math.MaxInt) // go/token doesn't have a METHOD token
 	TYPE       token.Token = token.TYPE
 	VAR        token.Token = token.VAR
+	initFunc   string      = "init"
 	mainMethod string      = "main"
+	testMain   string      = "TestMain"
 )
 
 var (
@@ -32,10 +38,17 @@ var (
 		TYPE:   3,
 		FUNC:   4,
 	}
-	newline = []byte("\n")
+	funcOrder = map[string]int{ // functions not listed come after these
+		mainMethod: -2,
+		initFunc:   -1,
+	}
+	newline          = []byte("\n")
+	testFuncPrefixes = []string{"Test", "Benchmark", "Example", "Fuzz"} // in the order they're sorted
 )
 
-type aifiFormatter struct{}
+type aifiFormatter struct {
+	config Config
+}
 
 // AifiFormatter is a code formatter that sorts Go declarations in the following order:
 // 1. Imports
@@ -45,26 +58,79 @@ type aifiFormatter struct{}
 // 5. Functions
 //
 // Within each category, declarations are sorted alphabetically, treating whole numbers in names as numeric values.
-// The "main" function always comes first among functions.
+// With Config.FoldCase, the alphabetical order ignores case.
+// With Config.ExportedFirst, exported declarations come before unexported ones within each category.
+// The "main" function comes first among functions, followed by any "init" functions in their original order,
+// since Go runs a file's init functions in the order they appear. Config.NoMainFirst sorts main with the rest.
+// In _test.go files, the remaining functions are grouped by kind: TestMain, tests, benchmarks, examples, fuzz tests,
+// and then helpers, or helpers first with Config.TestHelpersFirst.
 // Methods are sorted immediately after the type they belong to, and alphabetically by method name if multiple methods belong to the same type.
+// Methods of types declared in other files come after all the types, grouped by type.
+// Config.SortFuncs can keep functions and methods in source order instead, or put all methods after the functions;
+// see FuncOrder.
+// Methods named in Config.PriorityMethods come first among their type's methods, in the order listed.
+// With Config.Methods set to MethodOrderSource, a type's methods keep their source order instead.
+// With Config.GroupAliases, type aliases come before type definitions.
+// Config.Reorder limits sorting by name to the kinds of declarations it lists; the others keep their source order
+// within their category, and methods still follow their type.
+// With Config.SortSpecNames, the names declared together in a var spec without values, like "var b, a int", are sorted too.
 // Comments associated with declarations are preserved and moved along with their respective declarations.
-func (aifiFormatter) Format(filename string, src []byte) ([]byte, error) {
-	file, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.ParseComments|parser.SkipObjectResolution)
+// A comment block directly above a declaration, with no blank line in between, is its doc comment and goes with it.
+// Other comment blocks are free-floating: rather than moving with the declaration below them,
+// they stay at the top of its group, above the first of the declarations of the same category after sorting,
+// like a comment introducing a section. Methods count as types, unless FuncOrderReceiver puts them last.
+// With Config.KeepCommentsBelow, comment blocks below a declaration go with it instead, unless they're the doc comment
+// of the one after.
+// Only the spacing between declarations is rewritten; the text of each declaration is copied byte for byte,
+// so an import block keeps the blank lines gci puts between its groups. Everything before the first declaration,
+// such as build constraints or a "//usr/bin/env go run" line, is copied unchanged too,
+// indentation included; Config.NormalizeHeader re-indents the part before the package clause in a later stage.
+//
+// With Config.MinimalDiff, declarations that end up next to the same neighbor as in the source keep the original text between them,
+// so only moved declarations show up in a diff. Conceptually, the declarations already in order relative to each other
+// (the longest increasing subsequence of the source) stay put, and the rest are inserted at their sorted positions.
+// Since the kept declarations are sorted, that insertion yields the same order as a full sort;
+// the difference is that untouched neighbors aren't re-spaced.
+//
+// Sorting is all aifi does apart from that spacing, so Config.NoReorder skips the stage entirely, like --no-aifi.
+// Without it, declarations that weren't separated by a blank line stay that way, since gofmt only collapses
+// runs of blank lines and never adds them; the spacing within declarations is up to gofmt either way.
+//
+// Source is sliced by the byte offsets of the parsed file, and lines are compared as they are in the source,
+// so //line directives that remap reported positions don't affect the rewrite.
+func (f *aifiFormatter) Format(filename string, src []byte) ([]byte, error) {
+	isTestFile := strings.HasSuffix(filename, "_test.go")
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
 	if err != nil {
 		return nil, err
 	} else if len(file.Decls) == 0 {
 		return bytes.Clone(src), nil
 	}
 
-	decls := getDecls(file, src)
-	firstDeclStart := decls[0].Pos()
-	lastDeclEnd := decls[len(decls)-1].End()
-
-	slices.SortFunc(decls, func(a, b *declaration) int {
+	tokFile := fset.File(file.Pos())
+	if f.config.SortSpecNames {
+		if sorted := f.sortSpecNames(file, tokFile, src); sorted != nil {
+			return f.Format(filename, sorted) // sortSpecNames has nothing left to sort on this pass
+		}
+	}
+	decls := getDecls(file, tokFile, src, f.config.KeepCommentsBelow)
+	firstDeclStart := tokFile.Offset(decls[0].Pos())
+	lastDeclEnd := tokFile.Offset(decls[len(decls)-1].End())
+
+	localTypes := map[string]int{} // the original order of the declaration of each type in the file
+	for _, decl := range decls {
+		for _, spec := range lo.Ternary(decl.Tok == TYPE, decl.Specs, nil) {
+			localTypes[spec.(*ast.TypeSpec).Name.Name] = decl.OriginalOrder
+		}
+	}
+	sorted := lo.Filter(decls, func(decl *declaration, _ int) bool { return decl.Tok != COMMENT })
+	// Declarations that compare equal, like two func _, keep their order.
+	slices.SortStableFunc(sorted, func(a, b *declaration) int {
 		if a.Tok == METHOD {
-			return a.compareMethodToDecl(b)
+			return f.compareMethodToDecl(a, b, localTypes)
 		} else if b.Tok == METHOD {
-			return -b.compareMethodToDecl(a)
+			return -f.compareMethodToDecl(b, a, localTypes)
 		} else if a.Tok != b.Tok {
 			return cmp.Compare(declOrder[a.Tok], declOrder[b.Tok])
 		}
@@ -73,50 +139,114 @@ func (aifiFormatter) Format(filename string, src []byte) ([]byte, error) {
 		case IMPORT, CONST, VAR:
 			return cmp.Compare(a.OriginalOrder, b.OriginalOrder) // stable sort
 		case TYPE:
-			return compareStringsWithWholeNumbers(a.getTypeName(), b.getTypeName())
+			if !f.reorders("type") {
+				return cmp.Compare(a.OriginalOrder, b.OriginalOrder)
+			} else if f.config.GroupAliases && a.isAlias() != b.isAlias() {
+				return lo.Ternary(a.isAlias(), -1, 1)
+			}
+			return f.compareNames(a.getTypeName(), b.getTypeName())
 		case FUNC:
-			return compareFuncNames(a.getFunctionName(), b.getFunctionName())
+			return f.compareFuncs(a, b, isTestFile)
 		}
 		panic(fmt.Errorf("unsupported token.Token: %v", a.Tok))
 	})
+	comments := map[token.Token][]*declaration{} // free-floating comments, by the group of the declaration below them
+	for i, decl := range decls {
+		if decl.Tok == COMMENT {
+			group := f.group(decls[i+1])
+			comments[group] = append(comments[group], decl)
+		}
+	}
+	res := make([]*declaration, 0, len(decls))
+	for _, decl := range sorted {
+		res = append(res, comments[f.group(decl)]...)
+		delete(comments, f.group(decl))
+		res = append(res, decl)
+	}
 
-	rewritten := bytes.Join(lo.Map(decls, func(decl *declaration, _ int) []byte { return decl.Text }), newline)
-	return append(append(src[0:firstDeclStart-1], rewritten...), src[lastDeclEnd:]...), nil
+	return joinDecls(src, firstDeclStart, lastDeclEnd, res, f.separator), nil
 }
 
-// A Go declaration, either a function/method or a general declaration (import, const, type, var).
-type declaration struct {
-	ast.Node
-	Body          *ast.BlockStmt    // function body; or nil for external (non-Go) function
-	Doc           *ast.CommentGroup // associated documentation; or nil
-	Lparen        token.Pos         // position of '(', if any
-	Name          *ast.Ident        // function/method name
-	OriginalOrder int               // original order in source file, for stable sorting of imports, consts, and vars
-	Recv          *ast.FieldList    // receiver (methods); or nil (functions)
-	Rparen        token.Pos         // position of ')', if any
-	Specs         []ast.Spec        // *ImportSpec, *TypeSpec, or *ValueSpec, if any
-	Text          []byte            // original text of declaration
-	Tok           token.Token       //
-	TokPos        token.Pos         // position of Tok
-	Type          *ast.FuncType     // function signature: type and value parameters, results, and position of "func" keyword
+func (*aifiFormatter) Name() string {
+	return "aifi"
+}
+
+// Compare two functions according to the config.
+// The "main" function comes first unless Config.NoMainFirst is set, then "init" functions in their original order.
+// In test files, functions are then grouped by their test kind.
+// With FuncOrderNone, or without "func" in Config.Reorder, they keep their source order.
+func (f *aifiFormatter) compareFuncs(a, b *declaration, isTestFile bool) int {
+	if f.config.SortFuncs == FuncOrderNone || !f.reorders("func") {
+		return cmp.Compare(a.OriginalOrder, b.OriginalOrder)
+	}
+	aName, bName := a.getFunctionName(), b.getFunctionName()
+	if c := cmp.Compare(f.funcRank(aName), f.funcRank(bName)); c != 0 {
+		return c
+	} else if aName == initFunc && bName == initFunc {
+		return cmp.Compare(a.OriginalOrder, b.OriginalOrder)
+	} else if c := cmp.Compare(f.testFuncRank(aName), f.testFuncRank(bName)); isTestFile && c != 0 {
+		return c
+	}
+	return f.compareNames(aName, bName)
+}
+
+// Compare two method names of the same receiver type.
+// Names in Config.PriorityMethods come first, in the order listed; the rest are compared according to the config.
+func (f *aifiFormatter) compareMethodNames(a, b string) int {
+	if ai, bi := slices.Index(f.config.PriorityMethods, a), slices.Index(f.config.PriorityMethods, b); ai != bi {
+		if ai == -1 {
+			return 1
+		} else if bi == -1 {
+			return -1
+		}
+		return cmp.Compare(ai, bi)
+	}
+	return f.compareNames(a, b)
 }
 
 // Since methods are tied to types, we want to sort them immediately after the type declaration they belong to.
-// If multiple methods belong to the same type, sort them alphabetically by method name.
-func (decl *declaration) compareMethodToDecl(other *declaration) int {
-	receiverName := decl.getReceiverTypeName()
+// If multiple methods belong to the same type, sort the priority methods first and the rest alphabetically by method name.
+// Pointer and value receivers both group under the base type name, and no two methods of a type share a name,
+// so methods are ordered the same regardless of their receiver kind or their order in the source.
+// Methods of types declared in another file of the package, which aren't in localTypes, have no type to follow;
+// they come after all the types in the file, grouped and sorted by receiver type name, and before the functions.
+// With FuncOrderNone, a type's methods keep their source order,
+// and with FuncOrderReceiver, all methods come last, after the functions, grouped by receiver type.
+func (f *aifiFormatter) compareMethodToDecl(method, other *declaration, localTypes map[string]int) int {
+	receiverName := method.getReceiverTypeName()
+	if f.config.SortFuncs == FuncOrderReceiver {
+		if other.Tok != METHOD {
+			return 1
+		} else if c := f.compareNames(receiverName, other.getReceiverTypeName()); c != 0 {
+			return c
+		}
+		return f.compareMethods(method, other)
+	}
+
 	switch other.Tok {
 	case IMPORT, CONST, VAR:
 		return 1
 	case TYPE:
 		typeName := other.getTypeName()
-		if typeName == receiverName {
+		if _, isLocal := localTypes[receiverName]; !isLocal {
+			return 1
+		} else if f.config.GroupAliases && f.reorders("type") && other.isAlias() {
+			return 1 // methods go with the type definitions, after the aliases
+		} else if typeName == receiverName {
 			return 1 // method goes after the type declaration
 		}
-		return compareStringsWithWholeNumbers(receiverName, typeName)
+		return f.compareTypeNames(receiverName, typeName, localTypes)
 	case METHOD:
-		if c := compareStringsWithWholeNumbers(receiverName, other.getReceiverTypeName()); c == 0 {
-			return compareStringsWithWholeNumbers(decl.getFunctionName(), other.getFunctionName())
+		otherReceiverName := other.getReceiverTypeName()
+		_, isLocal := localTypes[receiverName]
+		if _, isOtherLocal := localTypes[otherReceiverName]; isLocal != isOtherLocal {
+			return lo.Ternary(isLocal, -1, 1)
+		} else if c := lo.Ternary(
+			isLocal,
+			f.compareTypeNames(receiverName, otherReceiverName, localTypes),
+			f.compareNames(receiverName, otherReceiverName),
+		); c == 0 {
+			return f.compareMethods(method, other)
 		} else {
 			return c
 		}
@@ -127,6 +257,135 @@ func (decl *declaration) compareMethodToDecl(other *declaration) int {
 	}
 }
 
+// Compare two methods of the same receiver type: in source order with MethodOrderSource, FuncOrderNone,
+// or without "method" in Config.Reorder, or else by name, priority methods first.
+func (f *aifiFormatter) compareMethods(a, b *declaration) int {
+	if f.config.Methods == MethodOrderSource || f.config.SortFuncs == FuncOrderNone || !f.reorders("method") {
+		return cmp.Compare(a.OriginalOrder, b.OriginalOrder)
+	}
+	return f.compareMethodNames(a.getFunctionName(), b.getFunctionName())
+}
+
+// Compare two declaration names, treating whole numbers in the names as numeric values.
+// With Config.ExportedFirst, exported names come before unexported ones.
+// With Config.FoldCase, names are compared case-insensitively first, then case-sensitively to break ties.
+func (f *aifiFormatter) compareNames(a, b string) int {
+	aExported, bExported := token.IsExported(a), token.IsExported(b)
+	if f.config.ExportedFirst && aExported != bExported {
+		return lo.Ternary(aExported, -1, 1)
+	} else if f.config.FoldCase {
+		if c := compareStringsWithWholeNumbers(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+			return c
+		}
+	}
+	return compareStringsWithWholeNumbers(a, b)
+}
+
+// Compare the names of two types declared in the file as the types are sorted:
+// by name, or by the order of their declarations without "type" in Config.Reorder.
+func (f *aifiFormatter) compareTypeNames(a, b string, localTypes map[string]int) int {
+	if !f.reorders("type") {
+		return cmp.Or(cmp.Compare(localTypes[a], localTypes[b]), f.compareNames(a, b)) // a declaration can have several
+	}
+	return f.compareNames(a, b)
+}
+
+// Rank a function name by funcOrder, treating main like any other name with Config.NoMainFirst.
+func (f *aifiFormatter) funcRank(name string) int {
+	if name == mainMethod && f.config.NoMainFirst {
+		return 0
+	}
+	return funcOrder[name]
+}
+
+// The group of declarations a free-floating comment above decl stays at the top of: its category,
+// with methods counted as types, since they're sorted among them, unless FuncOrderReceiver puts them in a group of their own.
+func (f *aifiFormatter) group(decl *declaration) token.Token {
+	if decl.Tok == METHOD && f.config.SortFuncs != FuncOrderReceiver {
+		return TYPE
+	}
+	return decl.Tok
+}
+
+// Report whether Config.Reorder lists kind, so declarations of that kind are sorted by name.
+func (f *aifiFormatter) reorders(kind string) bool {
+	return slices.Contains(f.config.Reorder, kind)
+}
+
+// The text to put between two declarations that end up next to each other.
+func (f *aifiFormatter) separator(prev, decl *declaration) []byte {
+	if f.config.MinimalDiff && prev.OriginalOrder+1 == decl.OriginalOrder {
+		return decl.Leading // still adjacent, so keep the original spacing
+	}
+	return newline
+}
+
+// Return src with the names of each top-level var spec without values sorted, or nil if they're all sorted already.
+// Specs with values are left alone, since the values pair up with the names by position,
+// as are const specs, which can implicitly repeat the values of the spec before them,
+// and specs with comments among their names.
+func (f *aifiFormatter) sortSpecNames(file *ast.File, tokFile *token.File, src []byte) []byte {
+	var res []byte
+	last := 0
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == VAR {
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.ValueSpec)
+				names := lo.Map(spec.Names, func(name *ast.Ident, _ int) string { return name.Name })
+				start, end := spec.Names[0].Pos(), spec.Names[len(spec.Names)-1].End()
+				if len(spec.Values) > 0 || slices.IsSortedFunc(names, f.compareNames) {
+					continue
+				} else if slices.ContainsFunc(file.Comments, func(group *ast.CommentGroup) bool {
+					return group.Pos() > start && group.End() < end
+				}) {
+					continue
+				}
+
+				slices.SortFunc(names, f.compareNames)
+				res = append(res, src[last:tokFile.Offset(start)]...)
+				res = append(res, strings.Join(names, ", ")...)
+				last = tokFile.Offset(end)
+			}
+		}
+	}
+	if res == nil {
+		return nil
+	}
+	return append(res, src[last:]...)
+}
+
+// Rank a function in a test file by its kind: TestMain, then the kinds in testFuncPrefixes.
+// Helpers rank after all of them, or before them with Config.TestHelpersFirst.
+func (f *aifiFormatter) testFuncRank(name string) int {
+	if name == testMain {
+		return 0
+	}
+	for i, prefix := range testFuncPrefixes {
+		if isTestFunc(name, prefix) {
+			return i + 1
+		}
+	}
+	return lo.Ternary(f.config.TestHelpersFirst, -1, len(testFuncPrefixes)+1)
+}
+
+// A Go declaration, either a function/method or a general declaration (import, const, type, var).
+type declaration struct {
+	ast.Node
+	Body          *ast.BlockStmt    // function body; or nil for external (non-Go) function
+	Doc           *ast.CommentGroup // associated documentation; or nil
+	Leading       []byte            // original text between the previous declaration and this one; or nil for the first
+	Lparen        token.Pos         // position of '(', if any
+	Name          *ast.Ident        // function/method name
+	OriginalOrder int               // original order in source file, for stable sorting of imports, consts, and vars
+	Recv          *ast.FieldList    // receiver (methods); or nil (functions)
+	Rparen        token.Pos         // position of ')', if any
+	Specs         []ast.Spec        // *ImportSpec, *TypeSpec, or *ValueSpec, if any
+	Text          []byte            // original text of declaration
+	Tok           token.Token       //
+	TokPos        token.Pos         // position of Tok
+	Type          *ast.FuncType     // function signature: type and value parameters, results, and position of "func" keyword
+}
+
 func (decl *declaration) getFunctionName() string {
 	if decl.Tok != FUNC && decl.Tok != METHOD {
 		return ""
@@ -134,7 +393,11 @@ func (decl *declaration) getFunctionName() string {
 	return decl.Name.Name
 }
 
-// If the declaration is a method, return the name of the receiver type (without any pointer or generic syntax).
+// If the declaration is a method, return the name of the receiver type (without any pointer, generic syntax, or parentheses).
+// Type parameters are dropped, so a receiver like *Map[A, B] matches "type Map[K comparable, V any]"
+// even though its type parameter names differ from the declaration's.
+// Receivers the compiler would reject, like a qualified pkg.T, still parse; they're named by their source text,
+// so such methods sort together by that text rather than next to any type.
 func (decl *declaration) getReceiverTypeName() string {
 	if decl.Tok != METHOD {
 		return ""
@@ -149,10 +412,12 @@ func (decl *declaration) getReceiverTypeName() string {
 			return rec(expr.X)
 		case *ast.IndexListExpr:
 			return rec(expr.X)
+		case *ast.ParenExpr:
+			return rec(expr.X)
 		case *ast.StarExpr:
 			return rec(expr.X)
 		default:
-			panic(fmt.Errorf("unsupported receiver type: %T", expr))
+			return types.ExprString(expr)
 		}
 	}
 	return rec(decl.Recv.List[0].Type)
@@ -166,6 +431,11 @@ func (decl *declaration) getTypeName() string {
 	return decl.Specs[0].(*ast.TypeSpec).Name.Name
 }
 
+// Report whether decl declares a type alias, like "type A = B".
+func (decl *declaration) isAlias() bool {
+	return decl.Tok == TYPE && decl.Specs[0].(*ast.TypeSpec).Assign.IsValid()
+}
+
 // A node that spans from the start of one node to the end of another.
 type rangeNode struct {
 	end   ast.Node
@@ -180,25 +450,20 @@ func (rn *rangeNode) Pos() token.Pos {
 	return rn.start.Pos()
 }
 
-// Compare two function names, treating whole numbers in the names as numeric values.
-// The "main" function always comes first.
-func compareFuncNames(a, b string) int {
-	if a == mainMethod {
-		return -1
-	} else if b == mainMethod {
-		return 1
-	}
-	return compareStringsWithWholeNumbers(a, b)
-}
-
 // Compare two strings, treating whole numbers in the strings as numeric values.
 // For example, "item2" < "item10" because 2 < 10.
+// Numbers with the same value but different leading zeros, like "a01b" and "a1b", are ordered by a plain byte comparison
+// of the whole strings, so the order is total and consistent however many digits each number has.
+// Signs aren't recognized: '-' compares as an ordinary byte, and the number after it as if it were positive,
+// so "temp-5" < "temp-10".
+// The names sorted here are Go identifiers, which can't contain '-', so signed numbers never come up.
 func compareStringsWithWholeNumbers(a, b string) int {
 	i, j := 0, 0
 	for i < len(a) && j < len(b) {
-		if a[i] != b[j] {
-			return cmp.Compare(a[i], b[j])
-		} else if !isDigit(a[i]) {
+		if !isDigit(a[i]) || !isDigit(b[j]) {
+			if a[i] != b[j] {
+				return cmp.Compare(a[i], b[j])
+			}
 			i++
 			j++
 			continue
@@ -220,11 +485,16 @@ func compareStringsWithWholeNumbers(a, b string) int {
 	} else if j < len(b) {
 		return -1
 	}
-	return 0
+	return cmp.Compare(a, b) // equal but for leading zeros
 }
 
 // Convert an ast.Decl to a *declaration, capturing the original source text.
-func getDecl(src []byte, decl ast.Decl, node ast.Node, order int) *declaration {
+// The text runs through the character following the declaration, normally its newline.
+// A declaration at the end of a file without a trailing newline gets one, so it can be moved anywhere.
+// The text of a declaration always ends with exactly one newline,
+// whatever followed it on its line in the source, such as blanks or a semicolon.
+func getDecl(src []byte, tokFile *token.File, decl ast.Decl, node ast.Node, order int) *declaration {
+	text := slices.Concat(src[tokFile.Offset(node.Pos()):tokFile.Offset(node.End())], newline)
 	switch decl := decl.(type) {
 	case *ast.FuncDecl:
 		return &declaration{
@@ -235,7 +505,7 @@ func getDecl(src []byte, decl ast.Decl, node ast.Node, order int) *declaration {
 			Name:          decl.Name,
 			OriginalOrder: order,
 			Recv:          decl.Recv,
-			Text:          src[node.Pos()-1 : node.End()],
+			Text:          text,
 			Type:          decl.Type,
 		}
 	case *ast.GenDecl:
@@ -246,7 +516,7 @@ func getDecl(src []byte, decl ast.Decl, node ast.Node, order int) *declaration {
 			OriginalOrder: order,
 			Rparen:        decl.Rparen,
 			Specs:         decl.Specs,
-			Text:          src[node.Pos()-1 : node.End()],
+			Text:          text,
 			Tok:           decl.Tok,
 			TokPos:        decl.TokPos,
 		}
@@ -256,54 +526,190 @@ func getDecl(src []byte, decl ast.Decl, node ast.Node, order int) *declaration {
 }
 
 // Since we're going to be moving around declarations, we need to do something with the comments.
-// Set the start of the Nth Decl to the start of the first comment block that comes after the end of the (N-1)th Decl.
-// This means multiple comment blocks between two Decls will all be prepended, in order, to the second Decl.
-// Comment blocks before the first Decl and after the last Decl are ignored.
-func getDecls(file *ast.File, src []byte) []*declaration {
-	leftBound := newlinePosAfterPackageDecl(file, src)
+// The comment block directly above the Nth Decl, with no blank line in between, is its doc comment,
+// so the Nth declaration starts at that block. That includes directives like //go:generate, //go:embed,
+// and compiler pragmas such as //go:noinline, which stay directly above the Decl they precede.
+// Any other comment blocks between the (N-1)th and Nth Decls are free-floating: they're returned together,
+// as a declaration of their own with Tok COMMENT, just before the Nth one.
+// A //go:embed directive can be separated from its var by blank lines and other // comments,
+// so for a var, the blocks from the first such directive on go with it as well.
+// Comment blocks after the last Decl are ignored.
+// /* */ comments count like // ones, whatever lines they span: a doc comment ends on the line above the Decl,
+// or on its first line, as in "/* doc */ func f() {}".
+// A comment on the same line as the end of a Decl trails it instead, and moves with it.
+// With keepBelow, so do the comment blocks below a Decl other than the next Decl's doc comment.
+func getDecls(file *ast.File, tokFile *token.File, src []byte, keepBelow bool) []*declaration {
+	leftBound := newlinePosAfterPackageDecl(file, tokFile, src)
 	if leftBound == token.NoPos {
-		leftBound = 1 // start of file
+		leftBound = tokFile.Pos(0) // start of file
 	}
 
-	res := make([]*declaration, len(file.Decls))
+	var res []*declaration
+	appendDecl := func(decl *declaration) {
+		if len(res) > 0 {
+			prevEnd := lineEnd(src, tokFile.Offset(res[len(res)-1].End()))
+			decl.Leading = src[prevEnd:max(prevEnd, tokFile.Offset(decl.Pos()))]
+		}
+		res = append(res, decl)
+	}
 	for i, j := 0, 0; i < len(file.Decls); i++ {
-		for j < len(file.Comments) && file.Comments[j].Pos() < leftBound {
+		for j < len(file.Comments) && file.Comments[j].End() <= leftBound {
 			j++ // skip all comments before the end of the last block
 		}
 
 		node := rangeNode{start: file.Decls[i], end: file.Decls[i]}
 		if j < len(file.Comments) && file.Comments[j].Pos() < file.Decls[i].Pos() {
-			node.start = file.Comments[j] // attach all comment blocks before this declaration to it
+			// The blocks from j through k are above this declaration. The first may start with the previous
+			// declaration's trailing comment, which isn't part of them.
+			first, _ := lo.Find(file.Comments[j].List, func(c *ast.Comment) bool { return c.Pos() >= leftBound })
+			k := j
+			for k+1 < len(file.Comments) && file.Comments[k+1].Pos() < file.Decls[i].Pos() {
+				k++
+			}
+			lastFloating := k
+			if sourceLine(tokFile, file.Comments[k].End())+1 >= sourceLine(tokFile, file.Decls[i].Pos()) {
+				lastFloating = k - 1 // block k is the doc comment
+				node.start = lo.Ternary[ast.Node](k == j, first, file.Comments[k])
+			}
+			if decl, ok := file.Decls[i].(*ast.GenDecl); ok && decl.Tok == VAR {
+				for m := lastFloating; m >= j && isLineComments(file.Comments[m]); m-- {
+					if hasEmbedDirective(file.Comments[m]) {
+						lastFloating = m - 1
+						node.start = lo.Ternary[ast.Node](m == j, first, file.Comments[m])
+					}
+				}
+			}
+			if lastFloating >= j {
+				floating := rangeNode{start: first, end: file.Comments[lastFloating]}
+				appendDecl(&declaration{
+					Node:          &floating,
+					OriginalOrder: len(res),
+					Text: slices.Concat(
+						src[tokFile.Offset(floating.Pos()):tokFile.Offset(floating.End())],
+						newline,
+					),
+					Tok: COMMENT,
+				})
+			}
+		}
+		if comment := trailingComment(file, tokFile, file.Decls[i]); comment != nil {
+			node.end = comment
+		}
+		if keepBelow {
+			var next ast.Decl
+			if i+1 < len(file.Decls) {
+				next = file.Decls[i+1]
+			}
+			if comment := lastCommentBelow(file, tokFile, node.End(), next); comment != nil {
+				node.end = comment
+			}
 		}
 
-		res[i] = getDecl(src, file.Decls[i], &node, i)
-		leftBound = file.Decls[i].End()
+		appendDecl(getDecl(src, tokFile, file.Decls[i], &node, len(res)))
+		leftBound = node.End()
 	}
 	return res
 }
 
+// Report whether group holds a //go:embed directive.
+func hasEmbedDirective(group *ast.CommentGroup) bool {
+	return slices.ContainsFunc(
+		group.List,
+		func(c *ast.Comment) bool { return strings.HasPrefix(c.Text, "//go:embed ") },
+	)
+}
+
 func isDigit(b byte) bool {
 	return b >= '0' && b <= '9'
 }
 
+// Report whether group is made of // comments only, which are all that may come between a directive and its declaration.
+func isLineComments(group *ast.CommentGroup) bool {
+	return !slices.ContainsFunc(group.List, func(c *ast.Comment) bool { return strings.HasPrefix(c.Text, "/*") })
+}
+
+// Report whether name is a test function of the kind given by prefix, following the go test naming rules:
+// the prefix alone, or followed by anything that doesn't start with a lowercase letter.
+func isTestFunc(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	} else if len(name) == len(prefix) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
+
+// Return src with the text from start to end, which spans all its declarations, replaced by decls in the order given,
+// each separated from the one before it by separator(prev, decl).
+func joinDecls(
+	src []byte,
+	start, end int,
+	decls []*declaration,
+	separator func(prev, decl *declaration) []byte,
+) []byte {
+	var rewritten []byte
+	for i, decl := range decls {
+		if i > 0 {
+			rewritten = append(rewritten, separator(decls[i-1], decl)...)
+		}
+		rewritten = append(rewritten, decl.Text...)
+	}
+	prefix := src[0:start]
+	if len(prefix) > 0 && !bytes.HasSuffix(prefix, newline) {
+		prefix = slices.Concat(bytes.TrimRight(prefix, " \t"), newline) // the first declaration shared a line with it
+	}
+	return slices.Concat(prefix, rewritten, src[lineEnd(src, end):])
+}
+
+// Return the last comment between end and next, or the end of the file if next is nil,
+// excluding next's doc comment: the block directly above it, with no blank line in between.
+func lastCommentBelow(file *ast.File, tokFile *token.File, end token.Pos, next ast.Decl) *ast.Comment {
+	i, _ := slices.BinarySearchFunc(file.Comments, end+1, func(group *ast.CommentGroup, pos token.Pos) int {
+		return cmp.Compare(group.End(), pos)
+	})
+
+	var res *ast.Comment
+	for _, group := range file.Comments[i:] {
+		if next != nil && group.Pos() >= next.Pos() {
+			break
+		} else if next != nil && sourceLine(tokFile, group.End())+1 >= sourceLine(tokFile, next.Pos()) {
+			break // next's doc comment
+		}
+		res = group.List[len(group.List)-1]
+	}
+	return res
+}
+
+// Return the offset just past the newline ending the line offset is on, if only blanks are left on it,
+// or else offset itself, so whatever follows on the same line is kept.
+func lineEnd(src []byte, offset int) int {
+	rest := bytes.TrimLeft(src[offset:], " \t")
+	if len(rest) == 0 {
+		return len(src)
+	} else if rest[0] == '\n' {
+		return len(src) - len(rest) + 1
+	}
+	return offset
+}
+
 // Find the position of the first newline character after the package declaration.
 // Returns token.NoPos if the package declaration is not found or if there is no newline after it.
-func newlinePosAfterPackageDecl(file *ast.File, src []byte) token.Pos {
+func newlinePosAfterPackageDecl(file *ast.File, tokFile *token.File, src []byte) token.Pos {
 	if file.Name == nil {
 		return token.NoPos
 	}
 
-	// Pos is 1-based, so subtract 1 to get a 0-based index into src.
-	// Then search for the next newline after the package declaration.
 	// The declaration starts at file.Package, which is the position of the 'p' in "package".
 	// We want to find the newline after the entire package declaration line.
 	// If there are comments on the same line, we still want to include them.
 	// So we look for the first newline character after the package declaration.
-	indexOfNewlineAfterPackage := bytes.Index(src[file.Package-1:], newline)
+	packageOffset := tokFile.Offset(file.Package)
+	indexOfNewlineAfterPackage := bytes.Index(src[packageOffset:], newline)
 	if indexOfNewlineAfterPackage == -1 {
 		return token.NoPos
 	}
-	return token.Pos(indexOfNewlineAfterPackage + int(file.Package)) // +1 to convert back to 1-based position
+	return tokFile.Pos(packageOffset + indexOfNewlineAfterPackage)
 }
 
 // Parse a whole number starting at index i in string s.
@@ -315,3 +721,28 @@ func parseNumber(s string, i int) (string, int) {
 	}
 	return strings.TrimLeft(s[i:j], "0"), j
 }
+
+// Return the line pos is on in the source, ignoring //line directives, which only change reported positions.
+func sourceLine(tokFile *token.File, pos token.Pos) int {
+	return tokFile.PositionFor(pos, false).Line
+}
+
+// Return the last comment on the line where decl ends, if any.
+func trailingComment(file *ast.File, tokFile *token.File, decl ast.Decl) *ast.Comment {
+	i, _ := slices.BinarySearchFunc(file.Comments, decl.End(), func(group *ast.CommentGroup, pos token.Pos) int {
+		return cmp.Compare(group.Pos(), pos)
+	})
+	if i == len(file.Comments) {
+		return nil
+	}
+
+	var res *ast.Comment
+	line := sourceLine(tokFile, decl.End())
+	for _, comment := range file.Comments[i].List {
+		if sourceLine(tokFile, comment.Pos()) != line {
+			break
+		}
+		res = comment
+	}
+	return res
+}
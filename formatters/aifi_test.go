@@ -0,0 +1,488 @@
+package formatters
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected.go golden files with the current output")
+
+// Declarations with the same sort key, like blank functions, keep their source order.
+// There are enough of them, among others out of order, that an unstable sort would mix them up.
+func TestAifiEqualKeysKeepSourceOrder(t *testing.T) {
+	var src, want, named strings.Builder
+	src.WriteString("package blank\n")
+	want.WriteString("package blank\n")
+	for i := range 50 {
+		fmt.Fprintf(&src, "\nfunc _() { println(%d) }\n\nfunc f%d() {}\n", i, 49-i)
+		fmt.Fprintf(&want, "\nfunc _() { println(%d) }\n", i)
+		fmt.Fprintf(&named, "\nfunc f%d() {}\n", i)
+	}
+	want.WriteString(named.String())
+
+	got, err := (&aifiFormatter{DefaultConfig()}).Format("blank.go", []byte(src.String()))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(got) != want.String() {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want.String())
+	}
+}
+
+// Each directory in testdata/aifi is a case: input.go is sorted with the default config
+// and compared to expected.go. Run with -update to regenerate expected.go after an intended change.
+func TestAifiGolden(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "aifi", "*"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(dirs) == 0 {
+		t.Fatal("no cases in testdata/aifi")
+	}
+
+	for _, dir := range dirs {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join(dir, "input.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := (&aifiFormatter{DefaultConfig()}).Format("input.go", input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedPath := filepath.Join(dir, "expected.go")
+			if *update {
+				if err := os.WriteFile(expectedPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			expected, err := os.ReadFile(expectedPath)
+			if err != nil {
+				t.Fatal(err)
+			} else if !bytes.Equal(got, expected) {
+				t.Errorf("output doesn't match %s; got:\n%s", expectedPath, got)
+			}
+		})
+	}
+}
+
+// Go runs a file's init functions in source order, so they keep it, right after main.
+func TestAifiInitOrder(t *testing.T) {
+	src := []byte(`package app
+
+func setup() {}
+
+func init() { println("second") }
+
+func main() {}
+
+func init() { println("first") }
+
+func helper() {}
+
+func init() { println("third") }
+`)
+	want := []string{
+		"main() {}",
+		`init() { println("second") }`,
+		`init() { println("first") }`,
+		`init() { println("third") }`,
+		"helper() {}",
+		"setup() {}",
+	}
+	got, err := (&aifiFormatter{DefaultConfig()}).Format("app.go", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var funcs []string
+	for _, line := range strings.Split(string(got), "\n") {
+		if name, ok := strings.CutPrefix(line, "func "); ok {
+			funcs = append(funcs, name)
+		}
+	}
+	if !slices.Equal(funcs, want) {
+		t.Errorf("got functions %q, want %q", funcs, want)
+	}
+}
+
+// aifi copies an import block byte for byte, so the blank lines gci puts between its sections survive.
+func TestAifiKeepsImportSections(t *testing.T) {
+	imports := `import (
+	"fmt"
+	"os"
+
+	"github.com/aifimmunology/tools"
+
+	"github.com/google/uuid"
+)
+`
+	src := "package foo\n\n" + imports + "\nfunc b() {}\n\nfunc a() { fmt.Println(os.Args, tools.X, uuid.New()) }\n"
+	got, err := (&aifiFormatter{DefaultConfig()}).Format("foo.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(got), "\n\n"+imports+"\n") {
+		t.Errorf("import block changed; got:\n%s", got)
+	}
+
+	sectioned, err := newGciFormatter(DefaultConfig()).Format("foo.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Contains(sectioned, []byte(imports)) {
+		t.Errorf("gci output doesn't have the expected sections; got:\n%s", sectioned)
+	}
+}
+
+func TestAifiMethodOrder(t *testing.T) {
+	src := []byte(`package methods
+
+type Server struct{}
+
+func (s *Server) Start() {}
+
+func (s *Server) Serve() {}
+
+func (s *Server) Close() {}
+`)
+	cases := map[MethodOrder][]string{
+		MethodOrderAlpha:  {"Close", "Serve", "Start"},
+		MethodOrderSource: {"Start", "Serve", "Close"},
+	}
+	for order, want := range cases {
+		t.Run(string(order), func(t *testing.T) {
+			config := DefaultConfig()
+			config.Methods = order
+			got, err := (&aifiFormatter{config}).Format("methods.go", src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(string(got), "\n")
+			var methods []string
+			for _, line := range lines {
+				if name, ok := strings.CutPrefix(line, "func (s *Server) "); ok {
+					methods = append(methods, strings.TrimSuffix(name, "() {}"))
+				}
+			}
+			if !slices.Equal(methods, want) {
+				t.Errorf("got methods %v, want %v", methods, want)
+			}
+		})
+	}
+}
+
+// With MinimalDiff, adding one declaration out of place moves only that declaration:
+// the spacing between the others, which were already in order, stays as it was.
+func TestAifiMinimalDiff(t *testing.T) {
+	src := `package foo
+
+var a = 1
+var b = 2
+
+type T int
+
+
+func c() {}
+
+func e() {}
+
+func d() {}
+`
+	cases := map[bool]string{
+		true: `package foo
+
+var a = 1
+var b = 2
+
+type T int
+
+
+func c() {}
+
+func d() {}
+
+func e() {}
+`,
+		false: `package foo
+
+var a = 1
+
+var b = 2
+
+type T int
+
+func c() {}
+
+func d() {}
+
+func e() {}
+`,
+	}
+	for minimalDiff, want := range cases {
+		t.Run(fmt.Sprint(minimalDiff), func(t *testing.T) {
+			config := DefaultConfig()
+			config.MinimalDiff = minimalDiff
+			got, err := (&aifiFormatter{config}).Format("foo.go", []byte(src))
+			if err != nil {
+				t.Fatal(err)
+			} else if string(got) != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+// Value and pointer receivers group under the same type and sort by name alone, priority methods first,
+// so the output is the same whatever order the methods start in.
+func TestAifiMixedReceivers(t *testing.T) {
+	methods := []string{
+		"func (f *Foo) Reset() {}\n",
+		"func (f Foo) Len() int { return 0 }\n",
+		"func (f *Foo) Add(int) {}\n",
+		"func (Foo) String() string { return \"\" }\n",
+		"func (*Foo) close() {}\n",
+	}
+	want := "package foo\n\ntype Foo struct{}\n\n" + strings.Join([]string{
+		methods[3], // a priority method
+		methods[2],
+		methods[1],
+		methods[0],
+		methods[4],
+	}, "\n") + "\nfunc New() *Foo { return nil }\n"
+
+	for i := range methods {
+		rotated := slices.Concat(methods[i:], methods[:i])
+		reversed := slices.Clone(rotated)
+		slices.Reverse(reversed)
+		for _, order := range [][]string{rotated, reversed} {
+			src := "package foo\n\nfunc New() *Foo { return nil }\n\n" + strings.Join(
+				order,
+				"\n",
+			) + "\ntype Foo struct{}\n"
+			got, err := (&aifiFormatter{DefaultConfig()}).Format("foo.go", []byte(src))
+			if err != nil {
+				t.Fatal(err)
+			} else if string(got) != want {
+				t.Errorf("from:\n%s\ngot:\n%s\nwant:\n%s", src, got, want)
+			}
+		}
+	}
+}
+
+func TestAifiNoMainFirst(t *testing.T) {
+	src := []byte(`package lib
+
+func zeta() {}
+
+func main() {}
+
+func alpha() {}
+`)
+	cases := map[bool][]string{
+		false: {"main", "alpha", "zeta"},
+		true:  {"alpha", "main", "zeta"},
+	}
+	for noMainFirst, want := range cases {
+		t.Run(fmt.Sprint(noMainFirst), func(t *testing.T) {
+			config := DefaultConfig()
+			config.NoMainFirst = noMainFirst
+			got, err := (&aifiFormatter{config}).Format("lib.go", src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var funcs []string
+			for _, line := range strings.Split(string(got), "\n") {
+				if name, ok := strings.CutPrefix(line, "func "); ok {
+					funcs = append(funcs, strings.TrimSuffix(name, "() {}"))
+				}
+			}
+			if !slices.Equal(funcs, want) {
+				t.Errorf("got functions %v, want %v", funcs, want)
+			}
+		})
+	}
+}
+
+// A file that doesn't end in a newline loses no bytes, and comes out ending in exactly one.
+func TestAifiNoTrailingNewline(t *testing.T) {
+	cases := map[string]struct{ src, want string }{
+		"last moves": {
+			"package foo\n\nfunc b() {}\n\nfunc a() { println(\"}\") }",
+			"package foo\n\nfunc a() { println(\"}\") }\n\nfunc b() {}\n",
+		},
+		"last stays": {
+			"package foo\n\nfunc b() {}\n\nfunc a() {}\n\nfunc c() {} // end",
+			"package foo\n\nfunc a() {}\n\nfunc b() {}\n\nfunc c() {} // end\n",
+		},
+		"trailing blanks": {
+			"package foo\n\nvar b = 1\n\nfunc a() {}\t ",
+			"package foo\n\nvar b = 1\n\nfunc a() {}\n",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := (&aifiFormatter{DefaultConfig()}).Format("foo.go", []byte(c.src))
+			if err != nil {
+				t.Fatal(err)
+			} else if string(got) != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+
+			got, err = NewFormatterWithConfig(DefaultConfig()).Format("foo.go", []byte(c.src))
+			if err != nil {
+				t.Fatal(err)
+			} else if string(got) != c.want {
+				t.Errorf("through the whole chain, got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestAifiReorder(t *testing.T) {
+	src := []byte(`package kinds
+
+type B int
+
+func (B) z() {}
+
+func (B) y() {}
+
+type A int
+
+func d() {}
+
+func c() {}
+`)
+	cases := map[string]struct {
+		reorder []string
+		want    []string
+	}{
+		"all":   {[]string{"func", "method", "type"}, []string{"A", "B", "y", "z", "c", "d"}},
+		"funcs": {[]string{"func", "method"}, []string{"B", "y", "z", "A", "c", "d"}},
+		"types": {[]string{"type"}, []string{"A", "B", "z", "y", "d", "c"}},
+		"none":  {[]string{}, []string{"B", "z", "y", "A", "d", "c"}},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Reorder = c.reorder
+			got, err := (&aifiFormatter{config}).Format("kinds.go", src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, line := range strings.Split(string(got), "\n") {
+				line = strings.TrimPrefix(line, "func (B) ")
+				if name, ok := strings.CutPrefix(line, "type "); ok {
+					names = append(names, strings.TrimSuffix(name, " int"))
+				} else if name, ok := strings.CutPrefix(line, "func "); ok {
+					names = append(names, strings.TrimSuffix(name, "() {}"))
+				} else if name, ok := strings.CutSuffix(line, "() {}"); ok {
+					names = append(names, name)
+				}
+			}
+			if !slices.Equal(names, c.want) {
+				t.Errorf("got %v, want %v", names, c.want)
+			}
+		})
+	}
+}
+
+// With SortSpecNames, the names of a var spec without values are sorted; those with values
+// are left alone, since the values pair up with the names by position, as are const specs.
+func TestAifiSortSpecNames(t *testing.T) {
+	cases := map[string]struct{ src, want string }{
+		"no values": {
+			"package foo\n\nvar c, a, b int\n",
+			"package foo\n\nvar a, b, c int\n",
+		},
+		"in a block": {
+			"package foo\n\nvar (\n\ty, x string\n\tn    int\n)\n",
+			"package foo\n\nvar (\n\tx, y string\n\tn    int\n)\n",
+		},
+		"call": {
+			"package foo\n\nvar b, a = f()\n",
+			"package foo\n\nvar b, a = f()\n",
+		},
+		"values": {
+			"package foo\n\nvar b, a = 2, 1\n",
+			"package foo\n\nvar b, a = 2, 1\n",
+		},
+		"const": {
+			"package foo\n\nconst (\n\tb, a = iota, -iota\n\td, c\n)\n",
+			"package foo\n\nconst (\n\tb, a = iota, -iota\n\td, c\n)\n",
+		},
+	}
+	config := DefaultConfig()
+	config.SortSpecNames = true
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := (&aifiFormatter{config}).Format("foo.go", []byte(c.src))
+			if err != nil {
+				t.Fatal(err)
+			} else if string(got) != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCompareStringsWithWholeNumbers(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"item2", "item10", -1},
+		{"item10", "item10", 0},
+		{"v1x", "v1", 1},
+		{"a9", "b1", -1},
+		{"x10y2", "x10y11", -1},
+		{"Item2", "item1", -1}, // bytes first, so uppercase sorts before lowercase
+		// Signs aren't recognized, so these compare by their digits alone: 10 > 5 even after a '-'.
+		{"temp-5", "temp-10", -1},
+		{"temp-1", "temp1", -1}, // '-' < '1'
+	}
+	for _, c := range cases {
+		if got := compareStringsWithWholeNumbers(c.a, c.b); got != c.want {
+			t.Errorf("compareStringsWithWholeNumbers(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		} else if got := compareStringsWithWholeNumbers(c.b, c.a); got != -c.want {
+			t.Errorf("compareStringsWithWholeNumbers(%q, %q) = %d, want %d", c.b, c.a, got, -c.want)
+		}
+	}
+}
+
+// Numbers that differ only in leading zeros compare equal, and the tiebreak on the whole strings keeps the order total:
+// antisymmetric, and the same however the names start out.
+func TestCompareStringsWithWholeNumbersLeadingZeros(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"a01b", "a1b", -1},
+		{"a001b", "a01b", -1},
+		{"a001b", "a1b", -1},
+		{"a01b", "a01b", 0},
+		{"a01c", "a1b", 1}, // the rest of the string decides before the leading zeros do
+		{"v010", "v10x", -1},
+		{"v010x", "v10", 1},
+	}
+	for _, c := range cases {
+		if got := compareStringsWithWholeNumbers(c.a, c.b); got != c.want {
+			t.Errorf("compareStringsWithWholeNumbers(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		} else if got := compareStringsWithWholeNumbers(c.b, c.a); got != -c.want {
+			t.Errorf("compareStringsWithWholeNumbers(%q, %q) = %d, want %d", c.b, c.a, got, -c.want)
+		}
+	}
+
+	want := []string{"a0b", "a001b", "a01b", "a1b", "a01c", "a2b", "a010b", "a10b"}
+	for i := range want {
+		names := slices.Concat(want[i:], want[:i])
+		if slices.SortFunc(names, compareStringsWithWholeNumbers); !slices.Equal(names, want) {
+			t.Errorf("sorted to %v, want %v", names, want)
+		}
+	}
+}
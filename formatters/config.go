@@ -0,0 +1,105 @@
+package formatters
+
+import (
+	"errors"
+	"fmt"
+	"go/version"
+	"slices"
+	"strings"
+)
+
+// The kinds of declarations Config.Reorder can list.
+// Constants and variables aren't among them: they always keep their source order, which their values may depend on.
+var reorderKinds = []string{"func", "method", "type"}
+
+// Config holds the settings used to build a Formatter.
+// Settings can be overridden per file with //gorganize: directives; see parseDirectives.
+type Config struct {
+	AddImports        bool        // add imports for referenced packages that aren't imported, resolved like goimports does
+	ChainSplitDots    bool        // let golines split a long method chain after its dots, one call per line
+	DisabledStages    []string    // names of default stages to skip; see DefaultStages
+	ExportedFirst     bool        // sort exported declarations before unexported ones
+	FixImports        bool        // remove unused imports before gci groups them
+	FoldCase          bool        // sort declaration names case-insensitively
+	GoVersion         string      // oldest Go version the output must build with, like "go1.21"; files using newer syntax fail
+	GolinesDotFile    string      // path golines writes a graph of each file it shortens to, in dot format, for debugging
+	GroupSpacing      int         // number of blank lines between top-level declarations
+	GroupAliases      bool        // sort type aliases before type definitions
+	KeepCommentsBelow bool        // keep comments below a declaration, other than the next one's doc comment, with it
+	LineEnding        LineEnding  // line ending of the formatted output
+	LocalPrefixes     []string    // import path prefixes gci puts in sections of their own, in order, after the standard library
+	MaxLen            int         // maximum line length before golines splits a line
+	Methods           MethodOrder // how the methods of a type are ordered among themselves
+	MinimalDiff       bool        // keep the original spacing between declarations that aren't moved apart
+	NoMainFirst       bool        // sort a function named main alphabetically rather than first
+	NoReorder         bool        // skip the aifi declaration sorter; see the note on aifiFormatter.Format
+	NormalizeHeader   bool        // indent the lines before the package clause with tabs; otherwise they're copied as is
+	PriorityMethods   []string    // method names sorted first among a type's methods, in the order given
+	ReformatTags      bool        // have golines align struct tags, in addition to splitting long lines
+	Reorder           []string    // kinds of declarations aifi sorts by name; the others keep their source order
+	ShortenComments   bool        // have golines split long comments too, which breaks long directives like //go:embed
+	Simplify          bool        // apply the simplifications of gofmt -s in the gofmt stage
+	SortFuncs         FuncOrder   // how functions and methods are ordered
+	SortSpecNames     bool        // sort the names declared together in a var spec without values
+	Stages            []string    // names of the stages to run, in order, including registered ones; nil for DefaultStages
+	TabLen            int         // width of a tab when golines measures line length
+	TestHelpersFirst  bool        // in _test.go files, sort helpers before test functions rather than after
+}
+
+// Validate reports the first invalid setting in the config, if any.
+func (config Config) Validate() error {
+	if err := config.LineEnding.validate(); err != nil {
+		return err
+	} else if err := config.SortFuncs.validate(); err != nil {
+		return err
+	} else if err := config.Methods.validate(); err != nil {
+		return err
+	} else if config.MaxLen <= 0 {
+		return fmt.Errorf("max line length must be positive, got %d", config.MaxLen)
+	} else if config.GroupSpacing < 0 {
+		return fmt.Errorf("group spacing can't be negative, got %d", config.GroupSpacing)
+	} else if config.TabLen <= 0 {
+		return fmt.Errorf("tab length must be positive, got %d", config.TabLen)
+	} else if config.GoVersion != "" && !version.IsValid(goVersion(config.GoVersion)) {
+		return fmt.Errorf("invalid Go version %q: must be like go1.21 or 1.21", config.GoVersion)
+	} else if slices.Contains(config.LocalPrefixes, "") {
+		return errors.New("local import prefixes can't be empty")
+	}
+	for _, kind := range config.Reorder {
+		if !slices.Contains(reorderKinds, kind) {
+			return fmt.Errorf(
+				"unknown declaration kind %q to reorder: must be one of %s",
+				kind,
+				strings.Join(reorderKinds, ", "),
+			)
+		}
+	}
+	known := slices.Concat(DefaultStages(), RegisteredStages())
+	for _, stage := range slices.Concat(config.DisabledStages, config.Stages) {
+		if !slices.Contains(known, stage) {
+			return fmt.Errorf("unknown stage %q: must be one of %s", stage, strings.Join(known, ", "))
+		}
+	}
+	for i, stage := range config.Stages {
+		if slices.Contains(config.Stages[:i], stage) {
+			return fmt.Errorf("stage %q is listed more than once", stage)
+		}
+	}
+	return nil
+}
+
+// DefaultConfig returns the settings gorganize uses when none are given.
+func DefaultConfig() Config {
+	return Config{
+		ChainSplitDots:  true,
+		GroupSpacing:    1,
+		LineEnding:      LineEndingAuto,
+		LocalPrefixes:   []string{"github.com/aifimmunology"},
+		MaxLen:          120,
+		Methods:         MethodOrderAlpha,
+		PriorityMethods: []string{"String", "Error"},
+		Reorder:         slices.Clone(reorderKinds),
+		SortFuncs:       FuncOrderAlpha,
+		TabLen:          4,
+	}
+}
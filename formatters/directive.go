@@ -0,0 +1,72 @@
+package formatters
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Directives are comment lines before the package clause of the form
+//
+//	//gorganize:setting setting ...
+//
+// where each setting is one of:
+//
+//	max-len=N   maximum line length for this file
+//	no-reorder  don't sort this file's declarations
+//	testdata    leave this file untouched, e.g. an intentionally unformatted test fixture or golden file
+//
+// Settings in a file take precedence over flags, which take precedence over the defaults.
+// When a setting appears more than once, the last one wins.
+const directivePrefix = "//gorganize:"
+
+var packageKeyword = []byte("package")
+
+// Per-file settings parsed from the directives of a source file.
+type directives struct {
+	maxLen    int // 0 if unset
+	noReorder bool
+	testdata  bool
+}
+
+// Override the settings in config with those given by the directives.
+func (d directives) apply(config Config) Config {
+	if d.maxLen != 0 {
+		config.MaxLen = d.maxLen
+	}
+	config.NoReorder = config.NoReorder || d.noReorder
+	return config
+}
+
+// Parse the directives before the package clause of src.
+// The source isn't parsed as Go, since files marked testdata are often deliberately malformed.
+func parseDirectives(src []byte) (directives, error) {
+	var d directives
+	for line := range bytes.Lines(src) {
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, packageKeyword) {
+			break
+		} else if !bytes.HasPrefix(line, []byte(directivePrefix)) {
+			continue
+		}
+
+		for _, setting := range strings.Fields(string(line[len(directivePrefix):])) {
+			switch key, value, _ := strings.Cut(setting, "="); key {
+			case "max-len":
+				if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+					return d, fmt.Errorf("invalid directive %q: max-len must be a positive integer", setting)
+				} else {
+					d.maxLen = n
+				}
+			case "no-reorder":
+				d.noReorder = true
+			case "testdata":
+				d.testdata = true
+			default:
+				return d, fmt.Errorf("unknown directive %q", setting)
+			}
+		}
+	}
+	return d, nil
+}
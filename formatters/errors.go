@@ -0,0 +1,67 @@
+package formatters
+
+import (
+	"errors"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// ErrNoPackageClause reports that the source doesn't start with a package clause, as every Go file must,
+// such as a fragment of generated code.
+var ErrNoPackageClause = errors.New("not a Go source file: missing package clause")
+
+// StageError reports that a stage failed on source an earlier stage changed, which points to a bug in the chain
+// rather than in the input. Input holds the source the stage failed on, to reproduce the failure with.
+type StageError struct {
+	Err      error
+	Input    []byte
+	Previous string // the last stage that changed the source
+	Stage    string
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s (in the %s stage, on the output of the %s stage)", e.Err, e.Stage, e.Previous)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// StagePanicError reports that a stage panicked, so that one file it can't handle fails on its own
+// rather than crashing a run over many files.
+type StagePanicError struct {
+	Stack []byte // stack of the panicking goroutine, for debugging
+	Stage string
+	Value any // the value passed to panic
+}
+
+func (e *StagePanicError) Error() string {
+	return fmt.Sprintf("%s stage panicked: %v", e.Stage, e.Value)
+}
+
+// Report whether src starts with a package clause, after any comments.
+func hasPackageClause(src []byte) bool {
+	var s scanner.Scanner
+	s.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0) // skips comments and a byte order mark
+	_, tok, _ := s.Scan()
+	return tok == token.PACKAGE
+}
+
+// Attribute err to filename, so it reads like "path/to/file.go:42:10: expected ';'".
+// Scanner errors get the filename set on their positions; other errors that don't already name the file are prefixed with it.
+func withFilename(filename string, err error) error {
+	var list scanner.ErrorList
+	if errors.As(err, &list) {
+		for _, e := range list {
+			if e.Pos.Filename == "" {
+				e.Pos.Filename = filename
+			}
+		}
+		return list
+	} else if strings.HasPrefix(err.Error(), filename) {
+		return err
+	}
+	return fmt.Errorf("%s: %w", filename, err)
+}
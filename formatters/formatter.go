@@ -1,34 +1,230 @@
 package formatters
 
-// order matters here
-var defaultFormatters = []formatter{
-	&gciFormatter{},
-	&golinesFormatter{},
-	&aifiFormatter{},
-	&gofmtFormatter{},
-}
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime/debug"
+	"slices"
+
+	"github.com/samber/lo"
+)
+
+// The filename Format passes to the formatter chain, for error messages.
+const sourceFilename = "source.go"
+
+var (
+	lineComment = []byte("//")
+	shebang     = []byte("#!")
+	utf8BOM     = []byte("\uFEFF")
+)
 
+// Formatter formats Go source by running it through a chain of stages.
+// A Formatter is safe for concurrent use: it isn't modified after it's created,
+// the default chain is built for each call, and none of the stages keep state between calls.
 type Formatter struct {
-	formatters []formatter
+	config     Config
+	formatters []Stage // nil for the default chain built from each file's settings
 }
 
-func (f *Formatter) Format(filename string, src []byte) (res []byte, err error) {
-	res = append(make([]byte, 0, len(src)), src...)
-	for _, formatter := range f.formatters {
-		if res, err = formatter.Format(filename, res); err != nil {
-			return nil, err
+// Format runs src through each formatter in the chain and returns the formatted source.
+// See FormatResult for details.
+func (f *Formatter) Format(filename string, src []byte) ([]byte, error) {
+	res, err := f.FormatResult(context.Background(), filename, src)
+	return res.Formatted, err
+}
+
+// FormatResult runs src through each formatter in the chain and reports what changed.
+// All stages work on LF line endings; CRLF is restored afterwards according to Config.LineEnding.
+// A leading UTF-8 byte order mark is stripped before the stages run and restored afterwards.
+// A leading #! line, accepted by some Go script runners though it isn't Go, is kept as is:
+// the stages see it as a // comment, so positions in errors still match src.
+// Directives in src override the Formatter's settings for this file; files marked testdata are returned unchanged,
+// as are empty files, which have no package clause to parse. Other files without one fail with ErrNoPackageClause.
+// With Config.GoVersion, files using syntax newer than that version fail before any stage runs.
+// If ctx is done before the chain finishes, the context's error is returned.
+// A stage that panics fails the file with a *StagePanicError rather than crashing the caller.
+// A stage that fails on the output of earlier stages, rather than on src, returns a *StageError.
+// src is never modified, and isn't copied up front unless ctx can be canceled, so Formatted may share memory with it.
+func (f *Formatter) FormatResult(ctx context.Context, filename string, src []byte) (Result, error) {
+	config, testdata, err := FileConfig(f.config, src)
+	if err != nil {
+		return Result{}, withFilename(filename, err)
+	} else if testdata || len(bytes.TrimSpace(src)) == 0 {
+		return Result{Formatted: bytes.Clone(src)}, nil
+	}
+
+	formatters := f.formatters
+	if formatters == nil {
+		formatters = defaultFormatters(config)
+	}
+
+	useCRLF := config.LineEnding == LineEndingCRLF || config.LineEnding == LineEndingAuto && isCRLFDominant(src)
+	hasBOM := bytes.HasPrefix(src, utf8BOM)
+	res := bytes.TrimPrefix(src, utf8BOM)
+	if bytes.Contains(res, crlf) {
+		res = bytes.ReplaceAll(res, crlf, newline)
+	} else if ctx.Done() != nil {
+		res = bytes.Clone(res) // a stage abandoned by runStage may read it after the caller reuses src
+	}
+	shebangLine := lo.Ternary(bytes.HasPrefix(res, shebang), firstLine(res), nil)
+	if shebangLine != nil {
+		res = slices.Concat(lineComment, res[len(shebang):])
+	}
+	if !hasPackageClause(res) {
+		return Result{}, withFilename(filename, ErrNoPackageClause)
+	}
+	if config.GoVersion != "" {
+		if err := checkGoVersion(filename, res, config.GoVersion); err != nil {
+			return Result{}, withFilename(filename, err)
 		}
 	}
-	return
+	var stages []string
+	for _, stage := range formatters {
+		if out, err := runStage(ctx, stage, filename, res); err != nil {
+			if len(stages) > 0 && ctx.Err() == nil {
+				return Result{}, &StageError{withFilename(filename, err), res, stages[len(stages)-1], stage.Name()}
+			}
+			return Result{}, withFilename(filename, err)
+		} else if !bytes.Equal(res, out) {
+			stages = append(stages, stage.Name())
+			res = out
+		}
+	}
+	if shebangLine != nil {
+		if !bytes.HasPrefix(res, lineComment) {
+			return Result{}, withFilename(filename, errors.New("a stage moved the #! line"))
+		}
+		res = slices.Concat(shebangLine, res[len(firstLine(res)):]) // gofmt may have reformatted the comment
+	}
+	if useCRLF {
+		res = bytes.ReplaceAll(res, newline, crlf)
+	}
+	if hasBOM {
+		res = slices.Concat(utf8BOM, res)
+	}
+	return Result{Changed: !bytes.Equal(src, res), Formatted: res, Stages: stages}, nil
 }
 
-type formatter interface {
+// Stage is one step of a Formatter's chain.
+// Format is called with the output of the previous stage, always with LF line endings and no byte order mark,
+// and must be safe for concurrent use. It must not modify src, which may be the caller's input.
+type Stage interface {
 	Format(filename string, src []byte) ([]byte, error)
+	Name() string // short name of the stage, e.g. "gofmt"
 }
 
-func NewFormatter(formatters ...formatter) *Formatter {
-	if len(formatters) == 0 {
-		formatters = defaultFormatters
+// DefaultStages returns the names of the stages in the default chain, in the order they run.
+func DefaultStages() []string {
+	return Stages(DefaultConfig())
+}
+
+// FileConfig returns config with the directives in src applied, as FormatResult applies them,
+// and whether src is marked testdata, which FormatResult returns unchanged.
+func FileConfig(config Config, src []byte) (Config, bool, error) {
+	d, err := parseDirectives(src)
+	if err != nil {
+		return Config{}, false, err
+	}
+	return d.apply(config), d.testdata, nil
+}
+
+// Format formats src as a non-test Go file with the default settings.
+// It's safe for concurrent use.
+func Format(src []byte) ([]byte, error) {
+	return NewFormatterWithConfig(DefaultConfig()).Format(sourceFilename, src)
+}
+
+func NewFormatter(formatters ...Stage) *Formatter {
+	return &Formatter{DefaultConfig(), formatters}
+}
+
+func NewFormatterWithConfig(config Config) *Formatter {
+	return &Formatter{config, nil}
+}
+
+// Stages returns the names of the stages in the default chain built from config, in the order they run.
+func Stages(config Config) []string {
+	return lo.Map(defaultFormatters(config), func(stage Stage, _ int) string { return stage.Name() })
+}
+
+// Run stage on src, returning a *StagePanicError if it panics.
+func callStage(stage Stage, filename string, src []byte) (out []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &StagePanicError{debug.Stack(), stage.Name(), r}
+		}
+	}()
+	return stage.Format(filename, src)
+}
+
+func defaultFormatters(config Config) []Stage {
+	// Order matters here.
+	// gci runs first and its import grouping survives the later stages:
+	// golines never splits import specs, aifi keeps import declarations in source order,
+	// and gofmt only sorts imports within the blank-line-separated groups gci produces.
+	// Config.Stages can change the order, giving up those guarantees, and add registered stages; see Register.
+	// The optional import fixes run before gci, so it groups the final set of imports.
+	stages := []Stage{
+		newGciFormatter(config),
+		newGolinesFormatter(config),
+		&aifiFormatter{config},
+		&gofmtFormatter{config.Simplify},
+	}
+	if len(config.Stages) > 0 {
+		byName := lo.Assign(registeredStages(), lo.KeyBy(stages, Stage.Name))
+		stages = lo.FilterMap(config.Stages, func(name string, _ int) (Stage, bool) {
+			stage, ok := byName[name]
+			return stage, ok
+		})
+	}
+
+	var formatters []Stage
+	if config.FixImports {
+		formatters = append(formatters, &unusedImportsFormatter{})
+	}
+	if config.AddImports {
+		formatters = append(formatters, &addImportsFormatter{})
+	}
+	formatters = append(formatters, stages...)
+	if config.NormalizeHeader {
+		formatters = append(formatters, &headerFormatter{config.TabLen})
+	}
+	if config.GroupSpacing != 1 {
+		formatters = append(formatters, &spacingFormatter{config.GroupSpacing}) // last, since gofmt would undo it
+	}
+	return slices.DeleteFunc(formatters, func(stage Stage) bool {
+		_, isAifi := stage.(*aifiFormatter)
+		return slices.Contains(config.DisabledStages, stage.Name()) || isAifi && config.NoReorder
+	})
+}
+
+// Return the first line of src, without its line ending.
+func firstLine(src []byte) []byte {
+	line, _, _ := bytes.Cut(src, newline)
+	return line
+}
+
+// Run a single stage, returning the context's error if ctx is done first.
+// Stages can't be interrupted, so an abandoned stage finishes in the background and its output is discarded.
+func runStage(ctx context.Context, stage Stage, filename string, src []byte) ([]byte, error) {
+	if ctx.Done() == nil {
+		return callStage(stage, filename, src) // never canceled
+	}
+
+	type result struct {
+		out []byte
+		err error
+	}
+	done := make(chan result, 1) // buffered, so an abandoned stage doesn't block forever
+	go func() {
+		out, err := callStage(stage, filename, src)
+		done <- result{out, err}
+	}()
+	select {
+	case res := <-done:
+		return res.out, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
-	return &Formatter{formatters}
 }
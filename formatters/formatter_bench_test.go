@@ -0,0 +1,104 @@
+package formatters
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/samber/lo"
+)
+
+// Benchmark inputs of increasing size, by their number of declarations; see benchSource.
+var benchSizes = []struct {
+	name  string
+	decls int
+}{{"small", 10}, {"medium", 100}, {"large", 1000}}
+
+func BenchmarkCompareStringsWithWholeNumbers(b *testing.B) {
+	pairs := [][2]string{
+		{"alpha", "bravo"},
+		{"item2", "item10"},
+		{"v1Beta12Release3", "v1Beta12Release20"},
+		{strings.Repeat("x", 64) + "1", strings.Repeat("x", 64) + "01"},
+	}
+	for b.Loop() {
+		for _, pair := range pairs {
+			compareStringsWithWholeNumbers(pair[0], pair[1])
+		}
+	}
+}
+
+func Benchmark_Format(b *testing.B) {
+	formatter := NewFormatterWithConfig(DefaultConfig())
+	for _, size := range benchSizes {
+		src := benchSource(size.decls)
+		b.Run(size.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(src)))
+			for b.Loop() {
+				if _, err := formatter.Format(size.name+".go", src); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Generate an unformatted file with n declarations: vars, functions, and struct types with methods, in a
+// shuffled order, with some parameter lists too long for one line, so every stage of the chain has work to do.
+// The same n always gives the same file.
+func benchSource(n int) []byte {
+	words := []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel", "india", "juliet"}
+	types := []string{"string", "int", "bool", "[]byte"}
+	rnd := rand.New(rand.NewPCG(1, uint64(n)))
+	name := func(i int, exported bool) string {
+		s := words[rnd.IntN(len(words))] + strings.ToUpper(
+			words[rnd.IntN(len(words))][:1],
+		) + words[rnd.IntN(len(words))][1:]
+		return lo.Ternary(exported, strings.ToUpper(s[:1])+s[1:], s) + strconv.Itoa(i)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("package bench\n\nimport (\n\t\"strings\"\n\t\"fmt\"\n\t\"os\"\n)\n")
+	var receiver string
+	for i := range n {
+		buf.WriteString("\n")
+		switch kind := rnd.IntN(4); {
+		case kind == 0:
+			fmt.Fprintf(
+				&buf,
+				"var %s = []string{%q, %q}\n",
+				name(i, rnd.IntN(2) == 0),
+				words[i%len(words)],
+				words[(i+1)%len(words)],
+			)
+		case kind == 1 && receiver != "":
+			fmt.Fprintf(&buf, "func (x *%s) %s() string {\n\treturn fmt.Sprint(x)\n}\n", receiver, name(i, true))
+		case kind == 1:
+			receiver = name(i, true)
+			fmt.Fprintf(&buf, "type %s struct {\n", receiver)
+			for j := range 1 + rnd.IntN(4) {
+				fmt.Fprintf(&buf, "\t%s %s\n", name(j, true), types[rnd.IntN(len(types))])
+			}
+			buf.WriteString("}\n")
+		default:
+			fn := name(i, rnd.IntN(2) == 0)
+			params := make([]string, 1+rnd.IntN(8))
+			for j := range params {
+				params[j] = words[rnd.IntN(len(words))] + strconv.Itoa(j) + " string"
+			}
+			fmt.Fprintf(
+				&buf,
+				"// %s does something with its arguments.\nfunc %s(%s) string {\n",
+				fn,
+				fn,
+				strings.Join(params, ", "),
+			)
+			fmt.Fprintf(&buf, "\treturn fmt.Sprintf(\"%%s %%s\", strings.ToUpper(%q), os.Args[0])\n}\n", fn)
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,421 @@
+package formatters
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// A stage whose output isn't valid Go, to simulate a bug in the chain.
+type brokenStage struct{}
+
+func (brokenStage) Format(_ string, src []byte) ([]byte, error) {
+	return append(bytes.Clone(src), "func {\n"...), nil
+}
+
+func (brokenStage) Name() string {
+	return "broken"
+}
+
+// Both settings of a combined directive apply, overriding the Formatter's.
+func TestFormatCombinedDirective(t *testing.T) {
+	src := `//gorganize:max-len=40 no-reorder
+
+package foo
+
+func b() {
+	println("first argument", "second argument")
+}
+
+func a() {}
+`
+	want := `//gorganize:max-len=40 no-reorder
+
+package foo
+
+func b() {
+	println(
+		"first argument",
+		"second argument",
+	)
+}
+
+func a() {}
+`
+	got, err := NewFormatterWithConfig(DefaultConfig()).Format("foo.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// Concurrent Format calls on one Formatter give the same results as sequential ones.
+// Run with -race to check that they're free of data races too.
+func TestFormatConcurrently(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*", "*", "input.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcs := make([][]byte, len(paths))
+	for i, path := range paths {
+		if srcs[i], err = os.ReadFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths = append(paths, "small.go", "medium.go")
+	srcs = append(srcs, benchSource(10), benchSource(100))
+
+	formatter := NewFormatterWithConfig(DefaultConfig())
+	wants := make([][]byte, len(paths))
+	for i, path := range paths {
+		if wants[i], err = formatter.Format(path, srcs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, path := range paths {
+				if got, err := formatter.Format(path, srcs[i]); err != nil {
+					t.Error(err)
+				} else if !bytes.Equal(got, wants[i]) {
+					t.Errorf("%s: got different output when formatted concurrently", path)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Disabling aifi, as --no-aifi does, keeps the declaration order while the other stages still run.
+func TestFormatDisabledAifi(t *testing.T) {
+	src := "package foo\nfunc  b() {\nreturn\n}\nfunc a() {}\n"
+	want := "package foo\n\nfunc b() {\n\treturn\n}\nfunc a() {}\n"
+
+	config := DefaultConfig()
+	config.DisabledStages = []string{"aifi"}
+	got, err := NewFormatterWithConfig(config).Format("foo.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// The input isn't copied before the first stage, so no stage may modify it.
+func TestFormatDoesNotModifyInput(t *testing.T) {
+	cases := map[string][]byte{
+		"bench":   benchSource(100),
+		"bom":     []byte("\uFEFFpackage foo\nfunc b() {}\nfunc a() {}\n"),
+		"crlf":    []byte("package foo\r\nfunc b() {}\r\nfunc a() {}\r\n"),
+		"imports": []byte("package foo\nimport (\n\"os\"\n\"fmt\"\n)\nvar _, _ = fmt.Sprint, os.Args\n"),
+		"shebang": []byte("#!/usr/bin/env gorun\npackage main\nfunc b() {}\nfunc main() {}\n"),
+	}
+	config := DefaultConfig()
+	config.FixImports = true
+	config.SortSpecNames = true
+	formatter := NewFormatterWithConfig(config)
+	for name, src := range cases {
+		t.Run(name, func(t *testing.T) {
+			original := bytes.Clone(src)
+			if _, err := formatter.Format("foo.go", src); err != nil {
+				t.Fatal(err)
+			} else if !bytes.Equal(src, original) {
+				t.Error("input was modified")
+			}
+		})
+	}
+}
+
+func TestFormatGoVersion(t *testing.T) {
+	src := []byte("package foo\n\ntype A = int\n\nfunc f[T any]() {}\n")
+	cases := map[string]string{
+		"1.8":    "foo.go:3:6: type aliases need go1.9, newer than the target go1.8",
+		"go1.17": "foo.go:5:1: type parameters need go1.18, newer than the target go1.17",
+		"1.18":   "",
+	}
+	for goVersion, want := range cases {
+		t.Run(goVersion, func(t *testing.T) {
+			config := DefaultConfig()
+			config.GoVersion = goVersion
+			_, err := NewFormatterWithConfig(config).Format("foo.go", src)
+			if got := fmt.Sprint(err); want == "" && err != nil || want != "" && got != want {
+				t.Errorf("got error %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+// A leading byte order mark is kept, and the file under it formats like any other.
+func TestFormatKeepsBOM(t *testing.T) {
+	src := "\uFEFF// Package foo has a BOM.\npackage foo\n\nimport (\n\"os\"\n\"fmt\"\n)\n\nfunc  b() {}\n\nfunc a() { fmt.Println(os.Args) }\n"
+	want := "\uFEFF// Package foo has a BOM.\npackage foo\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\n" +
+		"func a() { fmt.Println(os.Args) }\n\nfunc b() {}\n"
+
+	got, err := NewFormatterWithConfig(DefaultConfig()).Format("foo.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// golines never splits a directive, even one longer than the limit with Config.ShortenComments.
+func TestFormatKeepsEmbedDirective(t *testing.T) {
+	directive := "//go:embed static/a.txt static/b.txt static/c.txt static/d.txt static/e.txt static/f.txt\n"
+	src := "package foo\n\nimport \"embed\"\n\nfunc f() {}\n\n" + directive + "var static embed.FS\n"
+	want := "package foo\n\nimport \"embed\"\n\n" + directive + "var static embed.FS\n\nfunc f() {}\n"
+
+	config := DefaultConfig()
+	config.MaxLen = 60
+	config.ShortenComments = true
+	got, err := NewFormatterWithConfig(config).Format("foo.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// A #! line, and a //usr/bin/env one, survive byte for byte while the declarations below them are reordered.
+func TestFormatKeepsScriptLine(t *testing.T) {
+	cases := map[string]string{
+		"shebang": "#!/usr/bin/env -S go run  \n",
+		"comment": "//usr/bin/env go run \"$0\" \"$@\"; exit\n",
+	}
+	body := "//go:build ignore\n\npackage main\n\nfunc helper() {}\n\nfunc main() { helper() }\n"
+	want := "//go:build ignore\n\npackage main\n\nfunc main() { helper() }\n\nfunc helper() {}\n"
+	for name, line := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := NewFormatterWithConfig(DefaultConfig()).Format("script.go", []byte(line+body))
+			if err != nil {
+				t.Fatal(err)
+			} else if string(got) != line+want {
+				t.Errorf("got %q, want %q", got, line+want)
+			}
+		})
+	}
+}
+
+// Every stage runs on CRLF input, and the result keeps CRLF line endings throughout unless Config.LineEnding says
+// otherwise.
+func TestFormatLineEndings(t *testing.T) {
+	src := "package foo\n\nimport (\n\"os\"\n\"fmt\"\n)\n\nfunc  b() {}\n\nfunc a() {\n" +
+		"\tfmt.Println(\"a message long enough to be split over lines\", \"and another one\", os.Args)\n}\n"
+	want := "package foo\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc a() {\n\tfmt.Println(\n" +
+		"\t\t\"a message long enough to be split over lines\",\n\t\t\"and another one\",\n\t\tos.Args,\n\t)\n}\n\nfunc b() {}\n"
+	toCRLF := func(s string) string { return strings.ReplaceAll(s, "\n", "\r\n") }
+
+	cases := map[string]struct {
+		lineEnding LineEnding
+		src, want  string
+	}{
+		"auto with CRLF": {LineEndingAuto, toCRLF(src), toCRLF(want)},
+		"auto with LF":   {LineEndingAuto, src, want},
+		"crlf":           {LineEndingCRLF, src, toCRLF(want)},
+		"lf":             {LineEndingLF, toCRLF(src), want},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.MaxLen = 60
+			config.LineEnding = c.lineEnding
+			got, err := NewFormatterWithConfig(config).Format("foo.go", []byte(c.src))
+			if err != nil {
+				t.Fatal(err)
+			} else if string(got) != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+// golines runs after gci, but never splits import specs, so lines too long around an import block
+// leave it grouped as gci would group it last.
+func TestFormatLongLinesNearImports(t *testing.T) {
+	src := `package foo
+
+import (
+	veryLongAliasForTheStoragePackage "github.com/aifimmunology/platform/internal/storage/objectstore"
+	"os"
+	"github.com/google/uuid"
+	"fmt"
+)
+import anotherVeryLongAliasForTheSamePackage "github.com/aifimmunology/platform/internal/storage/blobs"
+
+var handlers = map[string]func(){"open": func() { fmt.Println(os.Args, uuid.New()) }, "close": nil}
+
+var _, _ = veryLongAliasForTheStoragePackage.New, anotherVeryLongAliasForTheSamePackage.New
+`
+	config := DefaultConfig()
+	config.MaxLen = 60
+	formatter := NewFormatterWithConfig(config)
+	got, err := formatter.Format("foo.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	regrouped, err := newGciFormatter(config).Format("foo.go", got)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(regrouped, got) {
+		t.Errorf("another gci pass changed the output:\n%s\nto:\n%s", got, regrouped)
+	}
+	again, err := formatter.Format("foo.go", got)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(again, got) {
+		t.Errorf("formatting again changed the output:\n%s\nto:\n%s", got, again)
+	}
+	want := `package foo
+
+import (
+	"fmt"
+	"os"
+
+	anotherVeryLongAliasForTheSamePackage "github.com/aifimmunology/platform/internal/storage/blobs"
+	veryLongAliasForTheStoragePackage "github.com/aifimmunology/platform/internal/storage/objectstore"
+
+	"github.com/google/uuid"
+)
+
+var handlers = map[string]func(){
+	"open":  func() { fmt.Println(os.Args, uuid.New()) },
+	"close": nil,
+}
+
+var _, _ = veryLongAliasForTheStoragePackage.New, anotherVeryLongAliasForTheSamePackage.New
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// Fragments without a package clause, as code generators sometimes produce, fail cleanly rather than in a stage.
+func TestFormatRejectsFragment(t *testing.T) {
+	cases := map[string]string{
+		"decls":    "func b() {}\nfunc a() {}\n",
+		"comments": "// Copyright 2024 The Authors.\n\n/* no package */\n",
+		"bom":      "\uFEFFvar x = 1\n",
+	}
+	for name, src := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Format([]byte(src))
+			if !errors.Is(err, ErrNoPackageClause) {
+				t.Errorf("got %v, want ErrNoPackageClause", err)
+			}
+		})
+	}
+}
+
+func TestFormatReportsStageInput(t *testing.T) {
+	src := []byte("package foo\n")
+	_, err := NewFormatter(brokenStage{}, &gofmtFormatter{}).Format("foo.go", src)
+
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) {
+		t.Fatalf("got %v, want a *StageError", err)
+	} else if stageErr.Stage != "gofmt" || stageErr.Previous != "broken" {
+		t.Errorf("got stage %q on the output of %q, want gofmt on the output of broken", stageErr.Stage, stageErr.Previous)
+	} else if want := "package foo\nfunc {\n"; string(stageErr.Input) != want {
+		t.Errorf("got input %q, want %q", stageErr.Input, want)
+	}
+
+	if _, err := NewFormatter(&gofmtFormatter{}).Format("foo.go", []byte("package foo\nfunc {\n")); errors.As(
+		err,
+		&stageErr,
+	) {
+		t.Errorf("got %v for invalid input, want a plain error", err)
+	}
+}
+
+// Files marked testdata are returned as they are, even if they aren't valid Go.
+func TestFormatSkipsTestdata(t *testing.T) {
+	cases := map[string]string{
+		"unsorted":  "//gorganize:testdata\n\npackage foo\nfunc b() {}\nfunc   a() {}\n",
+		"malformed": "// Deliberately broken.\n//gorganize:testdata\npackage foo\n\nfunc {\n",
+		"no clause": "//gorganize:testdata\nfunc b() {}\n",
+	}
+	formatter := NewFormatterWithConfig(DefaultConfig())
+	for name, src := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := formatter.Format("foo.go", []byte(src))
+			if err != nil {
+				t.Fatal(err)
+			} else if string(got) != src {
+				t.Errorf("got %q, want it unchanged", got)
+			}
+		})
+	}
+}
+
+// Files without declarations go through every stage of the default chain,
+// and formatted ones must come out unchanged, or --check would flag them on every run.
+func TestFormatStableWithoutDecls(t *testing.T) {
+	cases := map[string]string{
+		"empty":         "",
+		"blank":         "\n\n",
+		"package only":  "package foo\n",
+		"package doc":   "// Package foo does things.\npackage foo\n",
+		"build tag":     "//go:build linux\n\npackage foo\n",
+		"one import":    "package foo\n\nimport \"fmt\"\n",
+		"import block":  "package foo\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n",
+		"comment after": "package foo\n\n// TODO: add declarations.\n",
+	}
+	formatter := NewFormatterWithConfig(DefaultConfig())
+	for name, src := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := formatter.Format("foo.go", []byte(src))
+			if err != nil {
+				t.Fatal(err)
+			} else if string(got) != src {
+				t.Errorf("got %q, want it unchanged", got)
+			}
+		})
+	}
+}
+
+// Free-floating comments keep the blank lines around them, so they never become, or stop being, a doc comment.
+func TestGroupSpacing(t *testing.T) {
+	src := "package foo\n\nvar a int\n\n// floating note\n\nfunc c() {}\n\nfunc b() {}\n"
+	cases := map[int]string{
+		0: "package foo\n\nvar a int\n\n// floating note\n\nfunc b() {}\nfunc c() {}\n",
+		2: "package foo\n\nvar a int\n\n// floating note\n\nfunc b() {}\n\n\nfunc c() {}\n",
+	}
+	for blankLines, want := range cases {
+		t.Run(fmt.Sprint(blankLines), func(t *testing.T) {
+			config := DefaultConfig()
+			config.GroupSpacing = blankLines
+			got, err := NewFormatterWithConfig(config).Format("foo.go", []byte(src))
+			if err != nil {
+				t.Fatal(err)
+			} else if string(got) != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHeader(t *testing.T) {
+	src := "/*\n    Copyright 2024 The Authors.\n\n      Licensed under the Apache License.\n  \t  See LICENSE.\n*/\n\n" +
+		"package foo\n\nvar s = `\n    not header`\n"
+	want := "/*\n\tCopyright 2024 The Authors.\n\n\t  Licensed under the Apache License.\n\t  See LICENSE.\n*/\n\n" +
+		"package foo\n\nvar s = `\n    not header`\n"
+
+	config := DefaultConfig()
+	config.NormalizeHeader = true
+	got, err := NewFormatterWithConfig(config).Format("foo.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+package formatters
+
+import "fmt"
+
+const (
+	FuncOrderAlpha    FuncOrder = "alpha"    // main and init first, then alphabetically, with methods after their type
+	FuncOrderNone     FuncOrder = "none"     // source order, with methods still after their type
+	FuncOrderReceiver FuncOrder = "receiver" // functions as with alpha, then all methods, grouped by receiver type
+
+	MethodOrderAlpha  MethodOrder = "alpha"  // Config.PriorityMethods first, then alphabetically
+	MethodOrderSource MethodOrder = "source" // source order, e.g. for methods ordered by when they're called
+)
+
+// FuncOrder selects how aifi orders functions and methods.
+type FuncOrder string
+
+func (fo FuncOrder) validate() error {
+	switch fo {
+	case FuncOrderAlpha, FuncOrderNone, FuncOrderReceiver:
+		return nil
+	}
+	return fmt.Errorf(
+		"unsupported function order %q: must be one of %s, %s, %s",
+		fo,
+		FuncOrderAlpha,
+		FuncOrderNone,
+		FuncOrderReceiver,
+	)
+}
+
+// MethodOrder selects how aifi orders the methods of each type among themselves.
+type MethodOrder string
+
+func (mo MethodOrder) validate() error {
+	switch mo {
+	case MethodOrderAlpha, MethodOrderSource:
+		return nil
+	}
+	return fmt.Errorf("unsupported method order %q: must be one of %s, %s", mo, MethodOrderAlpha, MethodOrderSource)
+}
@@ -1,25 +1,40 @@
 package formatters
 
 import (
-	"github.com/daixiang0/gci/pkg/config"
+	gciconfig "github.com/daixiang0/gci/pkg/config"
 	"github.com/daixiang0/gci/pkg/gci"
+	"github.com/daixiang0/gci/pkg/log"
 	"github.com/daixiang0/gci/pkg/section"
 )
 
-var gciConfig = config.Config{
-	BoolConfig: config.BoolConfig{
-		CustomOrder:   true,
-		SkipGenerated: true,
-	},
-	Sections: section.SectionList{
-		section.Standard{},
-		section.Custom{Prefix: "github.com/aifimmunology"},
-		section.Default{}},
+// gciFormatter groups imports into standard library packages, then a section for each of Config.LocalPrefixes
+// in the order given, then everything else.
+// gci merges a file's import declarations into one, dropping duplicate imports of the same path and name.
+// A cgo import "C" is the exception: gci keeps it in a declaration of its own, directly below its preamble comment.
+type gciFormatter struct {
+	config gciconfig.Config
 }
 
-type gciFormatter struct{}
-
-func (gciFormatter) Format(filename string, src []byte) ([]byte, error) {
-	_, formatted, err := gci.LoadFormat(src, filename, gciConfig)
+func (f *gciFormatter) Format(filename string, src []byte) ([]byte, error) {
+	log.InitLogger() // gci logs through a global logger that must be set up first; only the first call does so
+	_, formatted, err := gci.LoadFormat(src, filename, f.config)
 	return formatted, err
 }
+
+func (*gciFormatter) Name() string {
+	return "gci"
+}
+
+func newGciFormatter(config Config) *gciFormatter {
+	sections := section.SectionList{section.Standard{}}
+	for _, prefix := range config.LocalPrefixes {
+		sections = append(sections, section.Custom{Prefix: prefix})
+	}
+	return &gciFormatter{gciconfig.Config{
+		BoolConfig: gciconfig.BoolConfig{
+			CustomOrder:   true, // keep the sections in the order above, rather than gci's default order
+			SkipGenerated: true,
+		},
+		Sections: append(sections, section.Default{}),
+	}}
+}
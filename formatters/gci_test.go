@@ -0,0 +1,78 @@
+package formatters
+
+import "testing"
+
+// The preamble of a cgo import "C" is its doc comment, so it must stay directly above it through the whole chain,
+// with "C" kept out of the other import groups.
+func TestCgoPreamble(t *testing.T) {
+	src := `package foo
+
+import (
+	"os"
+	"fmt"
+)
+
+// #include <stdio.h>
+// #include <stdlib.h>
+import "C"
+
+func b() {}
+
+func a() { fmt.Println(os.Args, C.int(1)) }
+`
+	want := `package foo
+
+// #include <stdio.h>
+// #include <stdlib.h>
+import "C"
+
+import (
+	"fmt"
+	"os"
+)
+
+func a() { fmt.Println(os.Args, C.int(1)) }
+
+func b() {}
+`
+	got, err := NewFormatterWithConfig(DefaultConfig()).Format("foo.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLocalPrefixes(t *testing.T) {
+	src := `package foo
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"myco/internal/store"
+	"myco/pkg/api"
+	"os"
+)
+`
+	want := `package foo
+
+import (
+	"fmt"
+	"os"
+
+	"myco/internal/store"
+
+	"myco/pkg/api"
+
+	"github.com/google/uuid"
+)
+`
+	config := DefaultConfig()
+	config.LocalPrefixes = []string{"myco/internal", "myco/pkg"}
+	got, err := newGciFormatter(config).Format("foo.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -1,9 +1,35 @@
 package formatters
 
-import "go/format"
+import (
+	"bytes"
+	"go/format"
+	"go/parser"
+	"go/token"
+)
 
-type gofmtFormatter struct{}
+// gofmtFormatter formats source like gofmt, or like gofmt -s with Config.Simplify; see simplify.
+type gofmtFormatter struct {
+	simplify bool
+}
+
+func (f gofmtFormatter) Format(filename string, src []byte) ([]byte, error) {
+	if !f.simplify {
+		return format.Source(src)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	simplify(file)
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
 
-func (gofmtFormatter) Format(_ string, src []byte) ([]byte, error) {
-	return format.Source(src)
+func (gofmtFormatter) Name() string {
+	return "gofmt"
 }
@@ -2,16 +2,37 @@ package formatters
 
 import "github.com/golangci/golines"
 
-var golinesShortener = golines.NewShortener(golines.ShortenerConfig{
-	ChainSplitDots:  true,
-	IgnoreGenerated: true,
-	MaxLen:          120,
-	ShortenComments: false,
-	TabLen:          4,
-})
+// golinesFormatter splits lines longer than Config.MaxLen. Its golines.ShortenerConfig comes from the Config:
+//
+//	MaxLen           Config.MaxLen           --max-len
+//	TabLen           Config.TabLen           tab_width or indent_size in .editorconfig
+//	ChainSplitDots   Config.ChainSplitDots   --chain-split-dots
+//	ReformatTags     Config.ReformatTags     --reformat-tags
+//	ShortenComments  Config.ShortenComments  --shorten-comments
+//	DotFile          Config.GolinesDotFile   --golines-dot-file
+//
+// IgnoreGenerated is always set, and golines has no setting for how a signature's parameters are split:
+// it tries to fit them on one line, and only then puts each on its own.
+type golinesFormatter struct {
+	shortener *golines.Shortener
+}
+
+func (f *golinesFormatter) Format(_ string, src []byte) ([]byte, error) {
+	return f.shortener.Shorten(src)
+}
 
-type golinesFormatter struct{}
+func (*golinesFormatter) Name() string {
+	return "golines"
+}
 
-func (golinesFormatter) Format(_ string, src []byte) ([]byte, error) {
-	return golinesShortener.Shorten(src)
+func newGolinesFormatter(config Config) *golinesFormatter {
+	return &golinesFormatter{golines.NewShortener(golines.ShortenerConfig{
+		ChainSplitDots:  config.ChainSplitDots,
+		DotFile:         config.GolinesDotFile,
+		IgnoreGenerated: true,
+		MaxLen:          config.MaxLen,
+		ReformatTags:    config.ReformatTags,
+		ShortenComments: config.ShortenComments,
+		TabLen:          config.TabLen,
+	})}
 }
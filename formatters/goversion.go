@@ -0,0 +1,97 @@
+package formatters
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/version"
+	"strings"
+)
+
+var (
+	featureBinaryLiteral  = goFeature{"0b, 0o, hex float, and digit-separated number literals", "go1.13"}
+	featureGenericAlias   = goFeature{"generic type aliases", "go1.24"}
+	featureRangeOverInt   = goFeature{"range loops over integers", "go1.22"}
+	featureTypeAlias      = goFeature{"type aliases", "go1.9"}
+	featureTypeParameters = goFeature{"type parameters", "go1.18"}
+)
+
+// A language feature and the Go version that introduced it.
+type goFeature struct {
+	name    string
+	version string
+}
+
+// Return an error for the first construct in src, in source order, that needs a newer Go than Config.GoVersion.
+// Only syntax is checked, since the file isn't type-checked: ranging over an integer is only found
+// when the integer is a literal, and newer library functions and range-over-func aren't found at all.
+// None of the stages add syntax of their own, so output that passes the check builds with that version too.
+func checkGoVersion(filename string, src []byte, target string) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return err
+	}
+
+	target = goVersion(target)
+	var res error
+	ast.Inspect(file, func(node ast.Node) bool {
+		if res != nil {
+			return false
+		}
+		var feature goFeature
+		switch node := node.(type) {
+		case *ast.BasicLit:
+			if isModernNumber(node) {
+				feature = featureBinaryLiteral
+			}
+		case *ast.FuncType:
+			if node.TypeParams != nil {
+				feature = featureTypeParameters
+			}
+		case *ast.RangeStmt:
+			if lit, ok := node.X.(*ast.BasicLit); ok && lit.Kind == token.INT {
+				feature = featureRangeOverInt
+			}
+		case *ast.TypeSpec:
+			if node.Assign.IsValid() && node.TypeParams != nil {
+				feature = featureGenericAlias
+			} else if node.Assign.IsValid() {
+				feature = featureTypeAlias
+			} else if node.TypeParams != nil {
+				feature = featureTypeParameters
+			}
+		}
+		if feature.version != "" && version.Compare(target, feature.version) < 0 {
+			res = fmt.Errorf(
+				"%s: %s need %s, newer than the target %s",
+				fset.Position(node.Pos()),
+				feature.name,
+				feature.version,
+				target,
+			)
+		}
+		return true
+	})
+	return res
+}
+
+// Return v with the "go" prefix go/version expects, so "1.21" and "go1.21" are the same version.
+func goVersion(v string) string {
+	if strings.HasPrefix(v, "go") {
+		return v
+	}
+	return "go" + v
+}
+
+// Report whether lit is a number literal in a form added in Go 1.13: 0b binary, 0o octal,
+// hexadecimal floating point, or with _ digit separators.
+func isModernNumber(lit *ast.BasicLit) bool {
+	if lit.Kind != token.INT && lit.Kind != token.FLOAT && lit.Kind != token.IMAG {
+		return false
+	}
+	value := strings.ToLower(lit.Value)
+	return strings.HasPrefix(value, "0b") || strings.HasPrefix(value, "0o") || strings.Contains(value, "_") ||
+		strings.HasPrefix(value, "0x") && strings.Contains(value, "p")
+}
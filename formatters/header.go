@@ -0,0 +1,50 @@
+package formatters
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"slices"
+
+	"github.com/samber/lo"
+)
+
+// headerFormatter re-indents the lines before the package clause, such as a license header, with tabs:
+// each Config.TabLen columns of leading whitespace become a tab, and any remainder stays as spaces.
+// gofmt doesn't touch the inside of comments and aifi copies everything before the first declaration unchanged,
+// so a header indented with spaces otherwise stays that way in files indented with tabs.
+// Only indentation at the start of a line changes; text after a // is the comment's own and is left alone.
+type headerFormatter struct {
+	tabLen int
+}
+
+func (f headerFormatter) Format(filename string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.PackageClauseOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	header := src[:fset.File(file.Pos()).Offset(file.Package)]
+	res := make([]byte, 0, len(src))
+	for line := range bytes.Lines(header) {
+		text := bytes.TrimLeft(line, " \t")
+		if len(bytes.TrimSpace(text)) == 0 {
+			res = append(res, line...) // blank lines are gofmt's business
+			continue
+		}
+
+		width := 0
+		for _, b := range line[:len(line)-len(text)] {
+			width = lo.Ternary(b == '\t', width/f.tabLen*f.tabLen+f.tabLen, width+1)
+		}
+		res = append(res, bytes.Repeat([]byte("\t"), width/f.tabLen)...)
+		res = append(res, bytes.Repeat([]byte(" "), width%f.tabLen)...)
+		res = append(res, text...)
+	}
+	return slices.Concat(res, src[len(header):]), nil
+}
+
+func (headerFormatter) Name() string {
+	return "header"
+}
@@ -0,0 +1,95 @@
+package formatters
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/samber/lo"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// unusedImportsFormatter removes the imports a file doesn't reference.
+// Without type information, an import is taken to be used if its name is the base of any selector
+// in the file, like "strings" in strings.Cut, so an import shadowed by a local variable is kept.
+// An import without an explicit name is assumed to have the name goimports would give it; see importName.
+// Blank, dot, and cgo imports have effects beyond their name and are always kept.
+type unusedImportsFormatter struct{}
+
+func (unusedImportsFormatter) Format(filename string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if sel, ok := node.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	removed := false
+	for _, spec := range slices.Clone(file.Imports) { // DeleteNamedImport removes from file.Imports
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || importPath == "C" {
+			continue
+		}
+		name := importName(spec.Name, importPath)
+		if name == "_" || name == "." || used[name] {
+			continue
+		}
+		file.Comments = slices.DeleteFunc(file.Comments, func(group *ast.CommentGroup) bool {
+			return group == spec.Doc || group == spec.Comment // would be left behind otherwise
+		})
+		removed = astutil.DeleteNamedImport(fset, file, lo.Ternary(spec.Name == nil, "", name), importPath) || removed
+	}
+	if !removed {
+		return bytes.Clone(src), nil
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (unusedImportsFormatter) Name() string {
+	return "imports"
+}
+
+// Return the name an import is referred to by: its explicit name if it has one, or else the name its package
+// is assumed to have, as goimports does. That's the last element of importPath, skipping a major version element
+// like "v2", without a "go-" prefix, and cut at the first character that can't be in an identifier,
+// as in "yaml" for gopkg.in/yaml.v3.
+func importName(name *ast.Ident, importPath string) string {
+	if name != nil {
+		return name.Name
+	}
+
+	base := path.Base(importPath)
+	if version, ok := strings.CutPrefix(base, "v"); ok {
+		if _, err := strconv.Atoi(version); err == nil && path.Dir(importPath) != "." {
+			base = path.Base(path.Dir(importPath))
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
@@ -0,0 +1,37 @@
+package formatters
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const (
+	LineEndingAuto LineEnding = "auto" // match the dominant line ending of the input
+	LineEndingCRLF LineEnding = "crlf"
+	LineEndingLF   LineEnding = "lf"
+)
+
+var crlf = []byte("\r\n")
+
+// LineEnding selects the line ending of the formatted output.
+type LineEnding string
+
+func (le LineEnding) validate() error {
+	switch le {
+	case LineEndingAuto, LineEndingCRLF, LineEndingLF:
+		return nil
+	}
+	return fmt.Errorf(
+		"unsupported line ending %q: must be one of %s, %s, %s",
+		le,
+		LineEndingAuto,
+		LineEndingCRLF,
+		LineEndingLF,
+	)
+}
+
+// Report whether more lines in src end in CRLF than in a bare LF.
+func isCRLFDominant(src []byte) bool {
+	crlfCount := bytes.Count(src, crlf)
+	return crlfCount > bytes.Count(src, newline)-crlfCount
+}
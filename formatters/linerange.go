@@ -0,0 +1,124 @@
+package formatters
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+
+	"github.com/samber/lo"
+)
+
+// The stages FormatRange runs. The others work on the whole file: gci regroups all the imports and aifi moves
+// declarations, so their changes can't be limited to a range.
+var rangeStages = []string{"golines", "gofmt"}
+
+// LineRange is a span of lines in a source file, numbered from 1 with both ends included, as editors report a selection.
+type LineRange struct {
+	Start int
+	End   int
+}
+
+func (r LineRange) validate() error {
+	if r.Start < 1 || r.End < r.Start {
+		return fmt.Errorf("invalid line range %d:%d: must be start:end with 1 <= start <= end", r.Start, r.End)
+	}
+	return nil
+}
+
+// FormatRange formats the top-level declarations of src with a line in lines, and returns the whole file
+// with the rest of it byte for byte as it was. A declaration's doc comment counts as part of it.
+// Each declaration is formatted as if it were alone in a file, by the golines and gofmt stages of the chain only,
+// so nothing moves between declarations and imports aren't regrouped.
+// Lines are numbered as they are in src, whatever //line directives say, and a leading #! line is kept
+// as FormatResult keeps it. Directives in src apply as they do for FormatResult,
+// and files marked testdata are returned unchanged.
+func (f *Formatter) FormatRange(filename string, src []byte, lines LineRange) ([]byte, error) {
+	if err := lines.validate(); err != nil {
+		return nil, err
+	}
+	config, testdata, err := FileConfig(f.config, src)
+	if err != nil {
+		return nil, withFilename(filename, err)
+	} else if testdata {
+		return bytes.Clone(src), nil
+	}
+	parsed := src
+	if bytes.HasPrefix(src, shebang) {
+		parsed = slices.Concat(lineComment, src[len(shebang):]) // the same length, so offsets still match src
+	}
+	if !hasPackageClause(parsed) {
+		return nil, withFilename(filename, ErrNoPackageClause)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, parsed, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	stages := f.formatters
+	if stages == nil {
+		stages = defaultFormatters(config)
+	}
+	stages = lo.Filter(stages, func(stage Stage, _ int) bool { return slices.Contains(rangeStages, stage.Name()) })
+	useCRLF := config.LineEnding == LineEndingCRLF || config.LineEnding == LineEndingAuto && isCRLFDominant(src)
+
+	res := bytes.Clone(src)
+	for _, decl := range slices.Backward(file.Decls) { // from the end, so the offsets of earlier ones stay valid
+		start, end := fset.PositionFor(declStart(decl), false), fset.PositionFor(decl.End(), false)
+		if start.Line > lines.End || end.Line < lines.Start {
+			continue
+		}
+
+		out, err := formatDecl(
+			stages,
+			filename,
+			file.Name.Name,
+			bytes.ReplaceAll(src[start.Offset:end.Offset], crlf, newline),
+		)
+		if err != nil {
+			return nil, withFilename(filename, fmt.Errorf("line %d: %w", start.Line, err))
+		}
+		if useCRLF {
+			out = bytes.ReplaceAll(out, newline, crlf)
+		}
+		res = slices.Concat(res[:start.Offset], out, res[end.Offset:])
+	}
+	return res, nil
+}
+
+// Return the position of decl's doc comment, or of decl itself if it has none.
+func declStart(decl ast.Decl) token.Pos {
+	var doc *ast.CommentGroup
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		doc = decl.Doc
+	case *ast.GenDecl:
+		doc = decl.Doc
+	}
+	if doc != nil {
+		return doc.Pos()
+	}
+	return decl.Pos()
+}
+
+// Run the text of a single declaration through stages, in a file of its own in package pkg,
+// and return the formatted declaration without a trailing newline.
+func formatDecl(stages []Stage, filename, pkg string, decl []byte) ([]byte, error) {
+	header := []byte("package " + pkg + "\n\n")
+	res := slices.Concat(header, decl, newline)
+	for _, stage := range stages {
+		out, err := callStage(stage, filename, res)
+		if err != nil {
+			return nil, err
+		}
+		res = out
+	}
+	if !bytes.HasPrefix(res, header) {
+		return nil, errors.New("a stage changed the package clause")
+	}
+	return bytes.TrimRight(res[len(header):], "\n"), nil
+}
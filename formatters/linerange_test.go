@@ -0,0 +1,44 @@
+package formatters
+
+import "testing"
+
+func TestFormatRange(t *testing.T) {
+	cases := map[string]struct {
+		src   string
+		lines LineRange
+		want  string
+	}{
+		"one declaration": {
+			"package foo\n\nfunc b() {\nreturn\n}\n\nfunc  a() {\nreturn\n}\n",
+			LineRange{7, 7},
+			"package foo\n\nfunc b() {\nreturn\n}\n\nfunc a() {\n\treturn\n}\n",
+		},
+		// Lines are the ones in the file, not the ones a //line directive reports.
+		"line directive": {
+			"package foo\n\n//line gen.tmpl:100\nfunc  a() {}\n\nfunc  b() {}\n",
+			LineRange{6, 6},
+			"package foo\n\n//line gen.tmpl:100\nfunc  a() {}\n\nfunc b() {}\n",
+		},
+		"remapped line": {
+			"package foo\n\n//line gen.tmpl:100\nfunc  a() {}\n\nfunc  b() {}\n",
+			LineRange{100, 100},
+			"package foo\n\n//line gen.tmpl:100\nfunc  a() {}\n\nfunc  b() {}\n",
+		},
+		"shebang": {
+			"#!/usr/bin/env gorun\npackage main\n\nfunc  main() {}\n",
+			LineRange{4, 4},
+			"#!/usr/bin/env gorun\npackage main\n\nfunc main() {}\n",
+		},
+	}
+	formatter := NewFormatterWithConfig(DefaultConfig())
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := formatter.FormatRange("foo.go", []byte(c.src), c.lines)
+			if err != nil {
+				t.Fatal(err)
+			} else if string(got) != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
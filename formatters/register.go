@@ -0,0 +1,43 @@
+package formatters
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+var (
+	registry   = map[string]Stage{}
+	registryMu sync.RWMutex
+)
+
+// Register makes a custom stage available by its name, to be run where Config.Stages lists it.
+// Registered stages don't run unless listed, so the default chain is unaffected.
+// Register is meant to be called from an init function; it panics if the name is empty
+// or already taken by a default or registered stage.
+func Register(stage Stage) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	name := stage.Name()
+	if name == "" {
+		panic("formatters: Register of a stage with no name")
+	} else if _, ok := registry[name]; ok || slices.Contains(DefaultStages(), name) {
+		panic(fmt.Sprintf("formatters: stage %q is already registered", name))
+	}
+	registry[name] = stage
+}
+
+// RegisteredStages returns the sorted names of the stages added with Register.
+func RegisteredStages() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return slices.Sorted(maps.Keys(registry))
+}
+
+func registeredStages() map[string]Stage {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return maps.Clone(registry)
+}
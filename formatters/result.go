@@ -0,0 +1,8 @@
+package formatters
+
+// Result describes the outcome of formatting one source file.
+type Result struct {
+	Changed   bool     // whether Formatted differs from the source
+	Formatted []byte   // the formatted source
+	Stages    []string // names of the stages that modified the source, in the order they ran
+}
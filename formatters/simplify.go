@@ -0,0 +1,109 @@
+package formatters
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+)
+
+func isBlank(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "_"
+}
+
+// Report whether expr is a call like len(name).
+func isLenOf(expr ast.Expr, name string) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 || call.Ellipsis.IsValid() {
+		return false
+	}
+	fun, ok := call.Fun.(*ast.Ident)
+	arg, isIdent := call.Args[0].(*ast.Ident)
+	return ok && fun.Name == "len" && isIdent && arg.Name == name
+}
+
+// Report whether two type expressions are written the same way.
+func sameType(a, b ast.Expr) bool {
+	return a != nil && b != nil && types.ExprString(a) == types.ExprString(b)
+}
+
+// Rewrite file with the simplifications of gofmt -s:
+//   - in a composite literal of array, slice, or map type, an element or key type that repeats the literal's is dropped,
+//     as is the & of a pointer element, so []*T{&T{}} becomes []*T{{}}
+//   - a slice expression s[a:len(s)] becomes s[a:]
+//   - a range clause "x, _ = range v" becomes "x = range v", and "_ = range v" becomes "range v"
+//   - declaration groups with nothing in them, like "const ()", are removed
+//
+// Like gofmt, s[a:len(s)] is rewritten without checking that len is the builtin, which it always is in practice.
+func simplify(file *ast.File) {
+	file.Decls = slices.DeleteFunc(file.Decls, func(decl ast.Decl) bool {
+		group, ok := decl.(*ast.GenDecl)
+		return ok && group.Doc == nil && len(group.Specs) == 0 && !slices.ContainsFunc(file.Comments,
+			func(comment *ast.CommentGroup) bool {
+				return group.Pos() <= comment.Pos() && comment.End() <= group.End()
+			})
+	})
+
+	var lits []*ast.CompositeLit
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.CompositeLit:
+			lits = append(lits, node)
+		case *ast.RangeStmt:
+			if isBlank(node.Value) {
+				node.Value = nil
+			}
+			if isBlank(node.Key) && node.Value == nil {
+				node.Key = nil
+			}
+		case *ast.SliceExpr:
+			if s, ok := node.X.(*ast.Ident); ok && node.Max == nil && isLenOf(node.High, s.Name) {
+				node.High = nil
+			}
+		}
+		return true
+	})
+	// Innermost first, so a nested literal is simplified while it still has its type.
+	for _, lit := range slices.Backward(lits) {
+		simplifyCompositeLit(lit)
+	}
+}
+
+func simplifyCompositeLit(lit *ast.CompositeLit) {
+	var keyType, eltType ast.Expr
+	switch typ := lit.Type.(type) {
+	case *ast.ArrayType:
+		eltType = typ.Elt
+	case *ast.MapType:
+		keyType, eltType = typ.Key, typ.Value
+	default:
+		return
+	}
+
+	for i := range lit.Elts {
+		elt := &lit.Elts[i]
+		if kv, ok := (*elt).(*ast.KeyValueExpr); ok {
+			if keyType != nil {
+				simplifyElement(keyType, &kv.Key)
+			}
+			elt = &kv.Value
+		}
+		simplifyElement(eltType, elt)
+	}
+}
+
+// Drop the type of the composite literal *elt if it's typ, or its & if typ is a pointer to it.
+func simplifyElement(typ ast.Expr, elt *ast.Expr) {
+	if inner, ok := (*elt).(*ast.CompositeLit); ok && sameType(inner.Type, typ) {
+		inner.Type = nil
+	}
+	if ptr, ok := typ.(*ast.StarExpr); ok {
+		if addr, ok := (*elt).(*ast.UnaryExpr); ok && addr.Op == token.AND {
+			if inner, ok := addr.X.(*ast.CompositeLit); ok && sameType(inner.Type, ptr.X) {
+				inner.Type = nil
+				*elt = inner
+			}
+		}
+	}
+}
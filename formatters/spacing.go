@@ -0,0 +1,40 @@
+package formatters
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+)
+
+// spacingFormatter puts Config.GroupSpacing blank lines between top-level declarations, instead of gofmt's one.
+// gofmt collapses runs of blank lines, so this stage runs last, and only when the spacing isn't 1.
+// The spacing within declarations, including between a declaration and the comments above it, is left alone,
+// as is the spacing around free-floating comments.
+type spacingFormatter struct {
+	blankLines int
+}
+
+func (f spacingFormatter) Format(filename string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	} else if len(file.Decls) == 0 {
+		return bytes.Clone(src), nil
+	}
+
+	tokFile := fset.File(file.Pos())
+	decls := getDecls(file, tokFile, src, false)
+	start, end := tokFile.Offset(decls[0].Pos()), tokFile.Offset(decls[len(decls)-1].End())
+	separator := bytes.Repeat(newline, f.blankLines)
+	return joinDecls(src, start, end, decls, func(prev, decl *declaration) []byte {
+		if prev.Tok == COMMENT || decl.Tok == COMMENT {
+			return decl.Leading // fewer blank lines could make a free-floating comment the doc comment of a declaration
+		}
+		return separator
+	}), nil
+}
+
+func (spacingFormatter) Name() string {
+	return "spacing"
+}
@@ -0,0 +1,26 @@
+// Package blocks has doc comments written as /* */ blocks.
+package blocks
+
+/*
+zulu is documented by a block comment
+spanning several lines.
+*/
+func zulu() {}
+
+/* mike has a one-line block doc comment. */
+func mike() {} /* and a trailing block comment */
+
+/*
+   A detached block comment, separated from alpha by a blank line.
+*/
+
+/* alpha's doc comment
+   ends on the line above it. */
+func alpha() {}
+
+/* bravo */ func bravo() {}
+
+var b = 2 /* bee
+spans two lines */
+
+var a = 1
@@ -0,0 +1,10 @@
+//go:build linux
+
+// Package boundaries has blanks and comments hugging its first and last declarations.
+package boundaries // with a trailing comment
+func zulu() {}   
+func mike() {}	
+func alpha() {} /* trailing */ // comments
+// A comment right after the last declaration.
+
+/* And one at the very end, without a final newline. */
\ No newline at end of file
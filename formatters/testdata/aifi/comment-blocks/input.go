@@ -0,0 +1,22 @@
+// Package blocks has declarations preceded by several comment blocks.
+package blocks
+
+func zulu() {}
+
+// The first block above alpha.
+// It has two lines.
+
+// The second block above alpha.
+
+/* A third, general comment block. */
+
+// alpha is documented.
+func alpha() {}
+
+// mike is between them.
+func mike() {}
+
+// A block above the type.
+
+// T is documented.
+type T int
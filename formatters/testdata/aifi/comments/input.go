@@ -0,0 +1,28 @@
+// Package comments has comments in all the places they can go.
+package comments
+
+// zulu is documented.
+func zulu() {} // and has a trailing comment
+
+// A detached comment about the next declaration.
+
+// alpha is documented too.
+//
+//go:noinline
+func alpha() {}
+
+var b = 2 // bee
+
+var a = 1 /* ay */
+
+// Section comment.
+
+type T struct {
+	// inside the type
+	Field int // the field
+}
+
+// mike is in the middle.
+func mike() {}
+
+// A comment after the last declaration.
@@ -0,0 +1,13 @@
+// Package detached has comments separated from the declaration below them by a blank line.
+package detached
+
+// A comment about the file's helpers, not about zulu.
+
+func zulu() {}
+
+// alpha is documented, so this comment moves with it.
+func alpha() {}
+
+// A note in the middle of the file.
+
+func mike() {}
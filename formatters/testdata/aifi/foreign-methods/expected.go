@@ -0,0 +1,23 @@
+// Package foreign has methods of types declared in another file of the package, Alpha and Zeta,
+// which sort after the types declared here.
+package foreign
+
+var x = 1
+
+type Beta int
+
+func (Beta) M() {}
+
+type Mid int
+
+func (m Mid) String() string { return "mid" }
+
+func (a Alpha) Go() {}
+
+func (z Zeta) Close() {}
+
+func (z *Zeta) Run() {}
+
+func alpha() {}
+
+func zulu() {}
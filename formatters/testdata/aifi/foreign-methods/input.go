@@ -0,0 +1,23 @@
+// Package foreign has methods of types declared in another file of the package, Alpha and Zeta,
+// which sort after the types declared here.
+package foreign
+
+func zulu() {}
+
+func (z *Zeta) Run() {}
+
+type Mid int
+
+func (a Alpha) Go() {}
+
+func (z Zeta) Close() {}
+
+func (m Mid) String() string { return "mid" }
+
+func alpha() {}
+
+type Beta int
+
+func (Beta) M() {}
+
+var x = 1
@@ -0,0 +1,23 @@
+package cache
+
+func (m *Map[Key, Value]) Put(k Key, v Value) { m.items[k] = v }
+
+type Set[T comparable] map[T]struct{}
+
+func (m Map[A, B]) Get(k A) (B, bool) {
+	v, ok := m.items[k]
+	return v, ok
+}
+
+type Map[K comparable, V any] struct {
+	items map[K]V
+}
+
+func (s Set[E]) Has(e E) bool {
+	_, ok := s[e]
+	return ok
+}
+
+func (m *Map[_, _]) Clear() { clear(m.items) }
+
+type Entry struct{}
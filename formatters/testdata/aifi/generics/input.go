@@ -0,0 +1,40 @@
+package generics
+
+func (l *List[T]) Push(v T) {
+	l.items = append(l.items, v)
+}
+
+func Map[T, U any](items []T, fn func(T) U) []U {
+	res := make([]U, len(items))
+	for i, item := range items {
+		res[i] = fn(item)
+	}
+	return res
+}
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+func (p Pair[K, V]) Swap() Pair[K, V] {
+	return p
+}
+
+type List[T any] struct {
+	items []T
+}
+
+func (l *List[T]) Len() int {
+	return len(l.items)
+}
+
+func Filter[T any](items []T, keep func(T) bool) []T {
+	var res []T
+	for _, item := range items {
+		if keep(item) {
+			res = append(res, item)
+		}
+	}
+	return res
+}
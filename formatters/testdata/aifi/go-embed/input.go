@@ -0,0 +1,24 @@
+package assets
+
+import "embed"
+
+func Open(name string) ([]byte, error) { return static.ReadFile(name) }
+
+// static holds the files served as they are.
+//
+//go:embed static/*
+var static embed.FS
+
+//go:embed templates/*.tmpl
+// The templates are parsed once, at startup.
+var templates embed.FS
+
+// A note about the schema, which isn't part of any declaration.
+
+//go:embed schema.sql
+
+// Applied by the migrations, in order.
+
+var schema string
+
+type Server struct{}
@@ -0,0 +1,13 @@
+package colors
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=Shade
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=Color
+type Color int
+
+func (c Color) Warm() bool { return c == Red }
+
+// Shade is how dark a Color is.
+type Shade int
+
+func (s Shade) Darker() Shade { return s + 1 }
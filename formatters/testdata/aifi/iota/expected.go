@@ -0,0 +1,29 @@
+package iota
+
+const (
+	Sunday Weekday = iota
+	Monday
+	Tuesday
+)
+
+const (
+	KB = 1 << (10 * (iota + 1))
+	MB
+	GB
+)
+
+const (
+	Red Color = iota
+	Green
+	Blue
+)
+
+var names = map[Weekday]string{}
+
+type Color int
+
+type Weekday int
+
+func (d Weekday) String() string {
+	return [...]string{"Sunday", "Monday", "Tuesday"}[d]
+}
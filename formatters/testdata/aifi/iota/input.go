@@ -0,0 +1,29 @@
+package iota
+
+type Weekday int
+
+func (d Weekday) String() string {
+	return [...]string{"Sunday", "Monday", "Tuesday"}[d]
+}
+
+const (
+	Sunday Weekday = iota
+	Monday
+	Tuesday
+)
+
+var names = map[Weekday]string{}
+
+const (
+	KB = 1 << (10 * (iota + 1))
+	MB
+	GB
+)
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	Blue
+)
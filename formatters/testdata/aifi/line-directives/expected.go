@@ -0,0 +1,22 @@
+package linedirectives
+
+/*line parser.y:3*/ var depth int
+
+//line parser.y:10
+
+type token int
+
+func (t token) String() string { return "token" } //line parser.y:5
+
+func at() {}
+
+// lex is generated from the lexer rules.
+//
+//line lexer.l:200
+func lex() {}
+
+//line parser.y:1
+func parse() {
+	//line parser.y:40
+	println("action")
+}
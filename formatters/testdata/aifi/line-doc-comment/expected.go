@@ -0,0 +1,9 @@
+package linedoc
+
+func a() {}
+
+//line foo.go:100
+func b() {}
+
+// c is declared in this file.
+func c() {}
@@ -0,0 +1,9 @@
+package linedoc
+
+// c is declared in this file.
+func c() {}
+
+//line foo.go:100
+func b() {}
+
+func a() {}
@@ -0,0 +1,33 @@
+package methods
+
+type Queue struct {
+	items []int
+}
+
+func (q *Queue) Len() int {
+	return len(q.items)
+}
+
+type Stack struct {
+	items []int
+}
+
+func (s Stack) String() string {
+	return "stack"
+}
+
+func (s *Stack) Error() string {
+	return "stack error"
+}
+
+func (s *Stack) Len() int {
+	return len(s.items)
+}
+
+func (s *Stack) Push(v int) {
+	s.items = append(s.items, v)
+}
+
+func NewStack() *Stack {
+	return &Stack{}
+}
@@ -0,0 +1,33 @@
+package methods
+
+func (s *Stack) Push(v int) {
+	s.items = append(s.items, v)
+}
+
+type Stack struct {
+	items []int
+}
+
+func NewStack() *Stack {
+	return &Stack{}
+}
+
+func (s Stack) String() string {
+	return "stack"
+}
+
+func (q *Queue) Len() int {
+	return len(q.items)
+}
+
+func (s *Stack) Len() int {
+	return len(s.items)
+}
+
+type Queue struct {
+	items []int
+}
+
+func (s *Stack) Error() string {
+	return "stack error"
+}
@@ -0,0 +1,19 @@
+package pragmas
+
+import _ "unsafe"
+
+func main() {
+	println(square(3), nanotime())
+}
+
+// cube is kept out of line for the benchmarks.
+//
+//go:noinline
+//go:nosplit
+func cube(x int) int { return x * x * x }
+
+//go:linkname nanotime runtime.nanotime
+func nanotime() int64
+
+//go:noinline
+func square(x int) int { return x * x }
@@ -0,0 +1,28 @@
+// Package receivers has methods with every receiver shape the parser accepts for a valid type.
+package receivers
+
+type Box[T any] struct{ v T }
+
+func (b *Box[_]) Clear() {}
+
+func (b Box[T]) Get() T { return b.v }
+
+type Pair[K comparable, V any] struct{}
+
+func (p Pair[K, V]) Key() K { var k K; return k }
+
+func (p *Pair[A, B]) Set(A, B) {}
+
+func (p *(Pair[K, V])) Swap() {}
+
+type Plain struct{}
+
+func (p (Plain)) Paren() {}
+
+func (p (*Plain)) ParenPointer() {}
+
+func (p *Plain) Pointer() {}
+
+func (p *(Plain)) PointerParen() {}
+
+func (Plain) Value() {}
@@ -0,0 +1,28 @@
+// Package receivers has methods with every receiver shape the parser accepts for a valid type.
+package receivers
+
+func (p *(Pair[K, V])) Swap() {}
+
+func (Plain) Value() {}
+
+func (p *Plain) Pointer() {}
+
+func (p (Plain)) Paren() {}
+
+func (p (*Plain)) ParenPointer() {}
+
+func (p *(Plain)) PointerParen() {}
+
+type Plain struct{}
+
+func (b Box[T]) Get() T { return b.v }
+
+func (b *Box[_]) Clear() {}
+
+type Pair[K comparable, V any] struct{}
+
+func (p Pair[K, V]) Key() K { var k K; return k }
+
+type Box[T any] struct{ v T }
+
+func (p *Pair[A, B]) Set(A, B) {}
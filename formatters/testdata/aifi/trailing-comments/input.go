@@ -0,0 +1,13 @@
+package answers
+
+func Zero() int { return 0 } // the base case
+
+type Question string // asked once
+
+var Answer = 42 // the answer
+
+const Unit = "" /* no unit */ // really none
+
+type Asker interface{ Ask() Question } // implemented by people
+
+func Ask(q Question) int { return Answer } // always the same
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// The files that differ from a git ref, by their absolute paths with symlinks resolved, as git reports them.
+type changedFiles map[string]bool
+
+// Report whether the file at path is one of the changed files, however path reaches it, e.g. through a symlink.
+func (files changedFiles) contains(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	} else if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	return files[abs]
+}
+
+// Return the files that differ from ref in the working tree, including untracked ones,
+// or nil if the current directory isn't in a git repository.
+func changedSince(ref string) (changedFiles, error) {
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, nil // not a repository, or no git at all
+	}
+	root = strings.TrimSpace(root)
+
+	changed, err := gitPaths("diff", "--name-only", "--diff-filter=d", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := gitPaths("ls-files", "--others", "--exclude-standard", "--full-name", root)
+	if err != nil {
+		return nil, err
+	}
+	res := changedFiles{}
+	for _, path := range slices.Concat(changed, untracked) {
+		res[filepath.Join(root, path)] = true
+	}
+	return res, nil
+}
+
+// Format the Go files staged in git and re-stage them, so a pre-commit hook commits formatted code.
+//
+// A partially staged file, with unstaged changes on top of its staged ones, can't be re-staged
+// without also staging those changes. Such files are formatted in the working tree only and reported
+// as an error, blocking the commit until the user reviews and stages the formatting themselves.
+func formatStaged(ctx context.Context) error {
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+	root = strings.TrimSpace(root)
+
+	staged, err := gitPaths("diff", "--cached", "--name-only", "--diff-filter=ACM")
+	if err != nil {
+		return err
+	}
+	unstaged, err := gitPaths("diff", "--name-only") // before formatting adds unstaged changes of its own
+	if err != nil {
+		return err
+	}
+
+	var partial []string
+	for _, path := range staged {
+		if err := ctx.Err(); err != nil {
+			return err
+		} else if !isGoFile(path) {
+			continue
+		} else if !summary.formatFile(ctx, filepath.Join(root, path)) {
+			continue // unchanged or failed
+		} else if slices.Contains(unstaged, path) {
+			partial = append(partial, path)
+		} else if _, err := git("add", "--", filepath.Join(root, path)); err != nil {
+			return err
+		}
+	}
+
+	if len(partial) > 0 {
+		return fmt.Errorf("formatted partially staged files without staging them: %s", strings.Join(partial, ", "))
+	}
+	return nil
+}
+
+// Run git with args and return its standard output.
+func git(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return string(out), err
+}
+
+// Run a git command that lists paths relative to the repository root, and return them.
+// The -z goes right after the subcommand, since anything after a "--" in args is a pathspec.
+func gitPaths(args ...string) ([]string, error) {
+	out, err := git(slices.Concat(args[:1], []string{"-z"}, args[1:])...)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(strings.Split(out, "\x00"), func(path string) bool { return path == "" }), nil
+}
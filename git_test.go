@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestChangedSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	// The repository is reached through a symlink, while git reports the paths in it with symlinks resolved.
+	dir := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(t.TempDir(), dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit := func(args ...string) {
+		t.Helper()
+		if _, err := git(append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(filepath.Join("sub", "a.go"), "package sub\n")
+	writeFile(filepath.Join("sub", "b.go"), "package sub\n")
+	writeFile(filepath.Join("sub", "gone.go"), "package sub\n")
+	runGit("init", "-q")
+	runGit("add", ".")
+	runGit("commit", "-qm", "initial")
+
+	writeFile(filepath.Join("sub", "b.go"), "package sub\n\nvar b int\n")
+	writeFile(filepath.Join("sub", "new.go"), "package sub\n")
+	if err := os.Remove(filepath.Join("sub", "gone.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := changedSince("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{
+		filepath.Join(dir, "sub", "a.go"):    false,
+		filepath.Join(dir, "sub", "b.go"):    true,
+		filepath.Join("sub", "b.go"):         true,
+		filepath.Join(dir, "sub", "gone.go"): false,
+		filepath.Join(dir, "sub", "new.go"):  true,
+	}
+	if len(changed) != 2 {
+		t.Errorf("got %v, want 2 files", changed)
+	}
+	for path, want := range want {
+		if got := changed.contains(path); got != want {
+			t.Errorf("contains(%s) = %t, want %t", path, got, want)
+		}
+	}
+
+	// So does walking the current directory, a symlink filepath.Walk wouldn't follow by itself.
+	roots, err := resolvePaths(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var walked []string
+	if err := walkGoFiles(roots, func(path string) error {
+		if changed.contains(path) {
+			walked = append(walked, filepath.Base(path))
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	} else if !slices.Equal(walked, []string{"b.go", "new.go"}) {
+		t.Errorf("walked changed files %v, want [b.go new.go]", walked)
+	}
+
+	if _, err := changedSince("no-such-ref"); err == nil {
+		t.Error("got no error for an unknown ref")
+	}
+}
+
+func TestChangedSinceOutsideRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(t.TempDir())) // in case the temporary directory is in a repository
+
+	if changed, err := changedSince("HEAD"); changed != nil || err != nil {
+		t.Errorf("got %v, %v; want nil, nil", changed, err)
+	}
+}
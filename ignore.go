@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// A file of paths to leave alone, read from each directory gorganize walks, in gitignore syntax:
+// blank lines and lines starting with # are skipped, a leading ! re-includes what an earlier pattern excluded,
+// a trailing / matches only directories, and a pattern with a slash in it is relative to the file's directory,
+// while one without matches names at any depth below it. The last matching pattern wins,
+// and patterns in subdirectories come after those of their parents.
+const ignoreFileName = ".gorganizeignore"
+
+// A pattern from an ignore file.
+type ignoreRule struct {
+	dir     string // directory of the ignore file, which the pattern is relative to
+	dirOnly bool
+	negate  bool
+	pattern string // doublestar pattern
+}
+
+// The rules that apply in a directory: those of its own ignore file and its parents', outermost first.
+type ignoreRules []ignoreRule
+
+// Report whether path, a directory if isDir is set, is excluded by the rules.
+func (rules ignoreRules) ignored(path string, isDir bool) bool {
+	res := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(rule.dir, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if matched, _ := doublestar.Match(rule.pattern, filepath.ToSlash(rel)); matched {
+			res = !rule.negate
+		}
+	}
+	return res
+}
+
+// Read the rules of the ignore file in dir, if there is one.
+func readIgnoreFile(dir string) (ignoreRules, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ignoreFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var rules ignoreRules
+	for lineNum, scanner := 1, bufio.NewScanner(bytes.NewReader(data)); scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		text, rule := line, ignoreRule{dir: dir}
+		if rule.negate = strings.HasPrefix(line, "!"); rule.negate {
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`) // escapes a leading # or !
+		if rule.dirOnly = strings.HasSuffix(line, "/"); rule.dirOnly {
+			line = strings.TrimRight(line, "/")
+		}
+		if !strings.Contains(line, "/") {
+			line = "**/" + line // matches at any depth
+		}
+		rule.pattern = strings.TrimPrefix(line, "/")
+		if !doublestar.ValidatePattern(rule.pattern) {
+			return nil, fmt.Errorf("%s:%d: invalid pattern %q", filepath.Join(dir, ignoreFileName), lineNum, text)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/autumnkelsey/gorganize/formatters"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func lintConfig(cmd *cobra.Command, args []string) error {
+	if err := resolveConfig(cmd); err != nil {
+		return err // the flags are checked first, since every file's settings start from them
+	}
+	roots, err := resolvePaths(args)
+	if err != nil {
+		return err
+	}
+
+	problems := 0
+	reported := map[string]bool{} // .editorconfig problems, which usually apply to many files
+	err = walkGoFiles(roots, func(path string) error {
+		for _, err := range lintEditorConfig(path) {
+			if !reported[err.Error()] {
+				fmt.Fprintf(os.Stderr, "gorganize: %s: %s\n", path, err)
+				reported[err.Error()] = true
+				problems++
+			}
+		}
+		if err := lintFileConfig(path); err != nil {
+			fmt.Fprintf(os.Stderr, "gorganize: %s: %s\n", path, err)
+			problems++
+		}
+		return nil
+	})
+	if err != nil {
+		return err // e.g. an invalid pattern in a .gorganizeignore file
+	} else if problems > 0 {
+		return fmt.Errorf("found %d problems in the settings", problems)
+	}
+	return nil
+}
+
+// Return the problems with the .editorconfig settings that apply to the file at path.
+// Malformed settings are otherwise skipped silently, leaving the defaults in place.
+func lintEditorConfig(path string) []error {
+	def, warning, err := editorConfig.LoadGraceful(path)
+	if err != nil {
+		return []error{err}
+	}
+
+	var problems []error
+	if warning != nil {
+		problems = append(problems, fmt.Errorf(".editorconfig: %w", warning))
+	}
+	if value, ok := def.Raw["max_line_length"]; ok && value != "off" {
+		if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+			problems = append(
+				problems,
+				fmt.Errorf(".editorconfig: max_line_length must be a positive integer or off, got %q", value),
+			)
+		}
+	}
+	return problems
+}
+
+// Return the problem with the settings for the Go file at path, with its directives applied, if there is one.
+func lintFileConfig(path string) error {
+	config, err := fileConfig(path)
+	if err != nil {
+		return err
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	} else if config, _, err = formatters.FileConfig(config, src); err != nil {
+		return err
+	} else if err := config.Validate(); err != nil {
+		return fmt.Errorf("with its directives and .editorconfig: %w", err)
+	}
+	return nil
+}
+
+func newLintConfigCommand(flags *pflag.FlagSet) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint-config [flags] [path ...]",
+		Short: "Check the settings for each file without formatting it",
+		Long: "Checks the flags, and for each Go file under the paths or packages (the current directory by default), " +
+			"the .editorconfig files, .gorganizeignore files, and //gorganize: directives that apply to it. " +
+			"Every problem found is reported, and the command fails if there are any.",
+		Args: cobra.ArbitraryArgs,
+		RunE: lintConfig,
+	}
+	cmd.Flags().AddFlagSet(flags)
+	return cmd
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// lint-config takes the same arguments as gorganize itself, package patterns like ./... included.
+func TestLintConfigPackagePattern(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                               "module example.com/lint\n\ngo 1.24\n",
+		"a.go":                                 "package lint\n",
+		filepath.Join("sub", "b.go"):           "//gorganize:max-len=0\n\npackage sub\n",
+		filepath.Join("sub", "deeper", "c.go"): "//gorganize:bogus\n\npackage deeper\n",
+		filepath.Join("sub", "deeper", "fine.go"): "//gorganize:max-len=100\n\npackage deeper\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+
+	cases := map[string]string{
+		"./...":            "found 2 problems in the settings",
+		"./sub/deeper/...": "found 1 problems in the settings",
+		"./sub/deeper":     "found 1 problems in the settings",
+		"a.go":             "",
+	}
+	for pattern, want := range cases {
+		t.Run(pattern, func(t *testing.T) {
+			err := lintConfig(&cobra.Command{}, []string{pattern})
+			if want == "" && err != nil || want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+				t.Errorf("got error %v, want %q", err, want)
+			}
+		})
+	}
+}
@@ -2,110 +2,925 @@ package main
 
 import (
 	"bytes"
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/autumnkelsey/gorganize/formatters"
-	"github.com/daixiang0/gci/pkg/log"
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/fatih/color"
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 )
 
 var (
-	debug     bool                  // for unit testing
-	formatter *formatters.Formatter = formatters.NewFormatter()
-	stdin     bool
+	backup        bool
+	cache         *formatCache // nil unless --cache is set
+	check         bool
+	checkVariants bool
+	config        formatters.Config = formatters.DefaultConfig()
+	debug         bool              // re-panic when a stage panics, for a stack trace
+	diffColor     bool
+	dryRun        bool
+	showDiff      bool // print a diff of each file that would change instead of writing it
+	excludes      []string
+	extensions    []string // besides .go
+	filesFrom     string
+	githubOutput  bool // print --check results as GitHub Actions annotations
+	includes      []string
+	jsonOutput    bool
+	maxDepth      int  = -1
+	maxLenSet     bool // --max-len was given, so it overrides .editorconfig
+	noColor       bool
+	noRecursive   bool
+	noWrite       bool
+	preCommit     bool // write files, but fail if any changed, so a pre-commit hook blocks the commit
+	quiet         bool
+	rangeLines    formatters.LineRange                                      // set by --range; zero to format all of standard input
+	readBuffers   = sync.Pool{New: func() any { return new(bytes.Buffer) }} // see readFile
+	showSummary   bool
+	since         string // git ref; only files that differ from it are formatted
+	staged        bool
+	stdin         bool
+	stdinFilename string = "<standard input>"
+	strict        bool
+	summary       runSummary
+	timeout       time.Duration
+	verbose       bool
+	watchMode     bool
+	write         bool
 )
 
+// A path to format, resolved from an argument by resolvePaths: a file, or a directory to walk.
+type walkRoot struct {
+	path string
+	flat bool // the directory of a package named without "...", whose subdirectories hold other packages
+}
+
 func main() {
+	// Flag descriptions are colored as they're defined, before flags are parsed, so look for --no-color up front.
+	// fatih/color already disables color when NO_COLOR is set or stdout isn't a terminal.
+	color.NoColor = color.NoColor || slices.ContainsFunc(os.Args[1:], isNoColorFlag)
+
 	cmd := &cobra.Command{
 		Use:   "gorganize [flags] [path ...]",
 		Short: "gorganize formats .go files.",
-		Long:  `Formats .go files based on the AIFI software team's coding conventions`,
-		RunE:  run,
+		Long: "Formats .go files based on the AIFI software team's coding conventions.\n\n" +
+			"Exits with status 0 on success, or 1 if any file failed to format, the flags are invalid, " +
+			"or with --check or --pre-commit, any file needed formatting.",
+		Args:    cobra.ArbitraryArgs,
+		RunE:    run,
+		Version: versionString(),
+
+		SilenceErrors: true,                                          // reported below
+		SilenceUsage:  slices.ContainsFunc(os.Args[1:], isQuietFlag), // even for invalid flags, parsed too late
 	}
+	cmd.AddCommand(newVersionCommand())
+	cmd.PersistentFlags().BoolVar(
+		&noColor,
+		"no-color",
+		false,
+		color.GreenString("Disable colored output (also set by NO_COLOR)"),
+	)
 
 	fs := cmd.Flags()
+	fs.BoolVar(
+		&config.AddImports,
+		"add-imports",
+		false,
+		color.GreenString("Add missing imports like goimports does; needs a populated module cache and can be slow"),
+	)
+	fs.BoolVar(&backup, "backup", false, color.GreenString("Save the original of each changed file to <path>.bak"))
+	fs.Bool(
+		"cache",
+		false,
+		color.GreenString("Skip files that are unchanged since gorganize last found them formatted"),
+	)
+	fs.BoolVar(
+		&config.ChainSplitDots,
+		"chain-split-dots",
+		config.ChainSplitDots,
+		color.GreenString(
+			"Split long method chains after their dots, one call per line; with false, only their arguments are split",
+		),
+	)
+	fs.BoolVar(
+		&checkVariants,
+		"check-variants",
+		false,
+		color.GreenString(
+			"Warn about build variants of a file, like foo_linux.go and foo_windows.go, that order declarations differently",
+		),
+	)
+	fs.BoolVar(&strict, "strict", false, color.GreenString("Fail on the warnings of --check-variants"))
+	fs.BoolVar(
+		&check,
+		"check",
+		false,
+		color.GreenString("Don't write files; list those that need formatting and fail if there are any"),
+	)
+	fs.BoolVar(
+		&config.ExportedFirst,
+		"exported-first",
+		false,
+		color.GreenString("Sort exported declarations before unexported ones"),
+	)
+	fs.BoolVarP(
+		&showDiff,
+		"diff",
+		"d",
+		false,
+		color.GreenString("Don't write files; print a unified diff of each one that would change, like gofmt -d"),
+	)
+	fs.BoolVar(
+		&diffColor,
+		"diff-color",
+		true,
+		color.GreenString("Color the output of --diff when standard output is a terminal and color isn't disabled"),
+	)
+	fs.BoolVar(
+		&dryRun,
+		"dry-run",
+		false,
+		color.GreenString("Don't write files; report each one that would change, with its size before and after"),
+	)
+	fs.StringArrayVar(
+		&excludes,
+		"exclude",
+		nil,
+		color.GreenString("Skip files and directories matching this glob, e.g. **/mocks/** or *_gen.go (repeatable)"),
+	)
+	fs.StringArrayVar(
+		&extensions,
+		"ext",
+		nil,
+		color.GreenString("Also format files with this extension as Go source, e.g. .go.out (repeatable)"),
+	)
+	for _, stage := range formatters.DefaultStages() {
+		fs.Bool("no-"+stage, false, color.GreenString("Skip the %s stage", stage))
+	}
+	fs.StringVar(
+		&filesFrom,
+		"files-from",
+		"",
+		color.GreenString("Format the files listed one per line in this file, or standard input if -"),
+	)
+	fs.BoolVar(&config.FixImports, "fix-imports", false, color.GreenString("Remove unused imports"))
+	fs.BoolVar(&config.FoldCase, "fold-case", false, color.GreenString("Sort declaration names case-insensitively"))
+	fs.StringSliceVar(
+		&config.Stages,
+		"formatters",
+		nil,
+		color.GreenString("Stages to run, in order (default %s)", strings.Join(formatters.DefaultStages(), ",")),
+	)
+	fs.IntVar(
+		&config.GroupSpacing,
+		"group-spacing",
+		config.GroupSpacing,
+		color.GreenString("Number of blank lines between top-level declarations"),
+	)
+	fs.BoolVar(
+		&githubOutput,
+		"github",
+		false,
+		color.GreenString(
+			"With --check, print GitHub Actions error annotations for files that need formatting "+
+				"(default true when GITHUB_ACTIONS=true)",
+		),
+	)
+	fs.StringVar(
+		&config.GoVersion,
+		"go-version",
+		"",
+		color.GreenString(
+			"Oldest Go version the code must build with, like 1.21; fail files using newer syntax, such as type parameters",
+		),
+	)
+	fs.StringVar(
+		&config.GolinesDotFile,
+		"golines-dot-file",
+		"",
+		color.GreenString("Write golines' graph of each file it shortens to this file, in dot format, for debugging"),
+	)
+	fs.BoolVar(
+		&config.GroupAliases,
+		"group-aliases",
+		false,
+		color.GreenString("Sort type aliases before type definitions"),
+	)
+	fs.StringArrayVar(
+		&includes,
+		"include",
+		nil,
+		color.GreenString("Format only files matching this glob (repeatable)"),
+	)
+	fs.BoolVar(
+		&jsonOutput,
+		"json",
+		false,
+		color.GreenString(
+			"Print a JSON array of {path, changed, error} results instead of human-readable output; "+
+				"with --stdin, read a {filename, source} object and print a {formatted, error} one",
+		),
+	)
+	fs.StringVar(
+		(*string)(&config.LineEnding),
+		"line-ending",
+		string(config.LineEnding),
+		color.GreenString("Line ending of formatted output: auto, crlf, or lf"),
+	)
+	fs.StringArrayVar(
+		&config.LocalPrefixes,
+		"local-prefix",
+		config.LocalPrefixes,
+		color.GreenString(
+			"Group imports with this path prefix after the standard library, a section per prefix in order (repeatable)",
+		),
+	)
+	fs.IntVar(
+		&maxDepth,
+		"max-depth",
+		maxDepth,
+		color.GreenString("Descend at most this many directory levels below each directory argument (-1 for no limit)"),
+	)
+	fs.IntVar(&config.MaxLen, "max-len", config.MaxLen, color.GreenString("Maximum line length before lines are split"))
+	fs.BoolVar(
+		&config.KeepCommentsBelow,
+		"keep-comments-below",
+		false,
+		color.GreenString("Keep comments below a declaration with it, unless they're the next one's doc comment"),
+	)
+	fs.StringVar(
+		(*string)(&config.Methods),
+		"methods",
+		string(config.Methods),
+		color.GreenString("Order of each type's methods: alpha, or source to keep them as written"),
+	)
+	fs.BoolVar(
+		&config.MinimalDiff,
+		"minimal-diff",
+		false,
+		color.GreenString("Keep the original spacing between declarations that aren't moved, to minimize diffs"),
+	)
+	fs.StringSliceVar(
+		&config.PriorityMethods,
+		"priority-methods",
+		config.PriorityMethods,
+		color.GreenString("Method names to sort first among a type's methods"),
+	)
+	fs.BoolVar(
+		&noRecursive,
+		"no-recursive",
+		false,
+		color.GreenString("Format only the Go files directly in each directory argument, not its subdirectories"),
+	)
+	fs.BoolVar(
+		&noWrite,
+		"no-write",
+		false,
+		color.GreenString(
+			"Never overwrite files; print every formatted file to standard output, even those found in directories",
+		),
+	)
+	fs.BoolVar(
+		&config.NoMainFirst,
+		"no-main-first",
+		false,
+		color.GreenString("Sort a function named main alphabetically instead of first, e.g. in a library"),
+	)
+	fs.BoolVar(
+		&config.NoReorder,
+		"no-reorder",
+		false,
+		color.GreenString("Keep declarations in their original order, while still running the other stages"),
+	)
+	fs.BoolVar(
+		&config.NormalizeHeader,
+		"normalize-header",
+		false,
+		color.GreenString(
+			"Indent comments before the package clause, like a license header, with tabs instead of spaces",
+		),
+	)
+	fs.BoolVar(
+		&preCommit,
+		"pre-commit",
+		false,
+		color.GreenString("Overwrite files like --write, but fail if any changed, listing them; for pre-commit hooks"),
+	)
+	fs.BoolVarP(&quiet, "quiet", "q", false, color.GreenString("Print nothing but errors; check the exit code instead"))
+	fs.String(
+		"range",
+		"",
+		color.GreenString(
+			"With --stdin, format only the top-level declarations on lines start:end (from 1, inclusive), "+
+				"with golines and gofmt alone, for an editor's format selection",
+		),
+	)
+	fs.BoolVar(
+		&config.ReformatTags,
+		"reformat-tags",
+		false,
+		color.GreenString("Align struct tags when splitting lines"),
+	)
+	fs.StringSliceVar(
+		&config.Reorder,
+		"reorder",
+		config.Reorder,
+		color.GreenString(
+			"Kinds of declarations to sort by name, from func, method, and type; the others keep their source order",
+		),
+	)
+	fs.StringVar(
+		&since,
+		"since",
+		"",
+		color.GreenString(
+			"Format only files that differ from this git ref, like origin/main, or are untracked; "+
+				"all files outside a git repository",
+		),
+	)
+	fs.BoolVar(
+		&config.ShortenComments,
+		"shorten-comments",
+		false,
+		color.GreenString("Split long comments too; this breaks directives longer than --max-len, like //go:embed"),
+	)
+	fs.BoolVarP(
+		&config.Simplify,
+		"simplify",
+		"s",
+		false,
+		color.GreenString("Simplify code like gofmt -s, e.g. []T{T{}} to []T{{}} and s[a:len(s)] to s[a:]"),
+	)
+	fs.Bool(
+		"sort-only",
+		false,
+		color.GreenString(
+			"Only sort declarations, keeping the original line lengths and spacing; same as --formatters=aifi --minimal-diff",
+		),
+	)
+	fs.StringVar(
+		(*string)(&config.SortFuncs),
+		"sort-funcs",
+		string(config.SortFuncs),
+		color.GreenString(
+			"Order of functions and methods: alpha, none (source order), or receiver (methods last, by type)",
+		),
+	)
+	fs.BoolVar(
+		&config.SortSpecNames,
+		"sort-spec-names",
+		false,
+		color.GreenString("Sort the names declared together in a var without values, like var b, a int"),
+	)
+	fs.BoolVar(&staged, "staged", false, color.GreenString("Format the Go files staged in git and re-stage them"))
 	fs.BoolVar(&stdin, "stdin", false, color.GreenString("Use standard input for piping source files"))
+	fs.StringVar(
+		&stdinFilename,
+		"stdin-filename",
+		stdinFilename,
+		color.GreenString("Path of the file piped with --stdin, used for import grouping and error messages"),
+	)
+	fs.BoolVar(
+		&showSummary,
+		"summary",
+		false,
+		color.GreenString("Print how many files were scanned, reformatted, and failed"),
+	)
+	fs.BoolVar(
+		&config.TestHelpersFirst,
+		"test-helpers-first",
+		false,
+		color.GreenString("In _test.go files, sort helper functions before test functions rather than after"),
+	)
+	fs.DurationVar(
+		&timeout,
+		"timeout",
+		0,
+		color.GreenString("Give up on a file that takes longer than this to format, e.g. 30s (0 for no limit)"),
+	)
+	fs.BoolVar(
+		&verbose,
+		"verbose",
+		false,
+		color.GreenString(
+			"Log each file to standard error as it's started and finished, with the stages that changed it",
+		),
+	)
+	fs.BoolVar(&watchMode, "watch", false, color.GreenString("Keep running and format Go files as they change"))
+	fs.BoolVarP(
+		&write,
+		"write",
+		"w",
+		false,
+		color.GreenString(
+			"Overwrite files named as arguments rather than printing them; files in directories are overwritten unless --no-write is set",
+		),
+	)
 
-	log.InitLogger()
+	fs.StringVar(&cpuProfile, "cpuprofile", "", color.GreenString("Write a CPU profile to this file"))
+	fs.StringVar(&memProfile, "memprofile", "", color.GreenString("Write a memory profile to this file when done"))
+	fs.BoolVar(&debug, "debug", false, color.GreenString("Crash with a stack trace when a stage panics"))
+	_ = fs.MarkHidden("cpuprofile")
+	_ = fs.MarkHidden("debug")
+	_ = fs.MarkHidden("memprofile")
+
+	cmd.MarkFlagsMutuallyExclusive("files-from", "since", "staged", "stdin", "watch")
+	cmd.MarkFlagsMutuallyExclusive("check", "diff", "dry-run", "pre-commit", "watch")
+	cmd.MarkFlagsMutuallyExclusive("pre-commit", "staged", "stdin")
+	cmd.MarkFlagsMutuallyExclusive("json", "quiet", "summary")
+	cmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+	cmd.MarkFlagsMutuallyExclusive("formatters", "sort-only")
+	cmd.MarkFlagsMutuallyExclusive("no-reorder", "sort-only")
+	for _, flag := range []string{"backup", "pre-commit", "staged", "watch", "write"} {
+		cmd.MarkFlagsMutuallyExclusive("no-write", flag) // each of them writes files
+	}
+
+	cmd.AddCommand(newConfigCommand(fs), newLintConfigCommand(fs)) // after the flags they share are defined
 
 	if err := cmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "gorganize failed: %s", err.Error())
+		fmt.Fprintf(os.Stderr, "gorganize failed: %s\n", err.Error())
 		os.Exit(1)
 	}
 }
 
-func formatFiles(args []string) error {
-	var paths []string
-	if debug {
-		paths = []string{"./formatters/aifi.go"}
-	} else if len(args) == 0 {
-		if abs, err := filepath.Abs("."); err != nil {
+// The number of directory levels dir is below root, which contains it.
+func dirDepth(root, dir string) int {
+	if rel, err := filepath.Rel(root, dir); err != nil || rel == "." {
+		return 0
+	} else {
+		return strings.Count(rel, string(filepath.Separator)) + 1
+	}
+}
+
+// Format the Go file at path, overwriting it if it changed.
+func formatFile(ctx context.Context, path string) (changed bool, err error) {
+	input, release, err := readFile(path)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	if config, err := fileConfig(path); err != nil {
+		return false, err
+	} else if cache.has(config, path, input) {
+		recordDeclOrder(path, input)
+		return false, nil
+	} else if output, err := formatSource(ctx, config, path, input); err != nil {
+		return false, err
+	} else if bytes.Equal(input, output) {
+		cache.add(config, path, input)
+		return false, nil
+	} else if check {
+		return true, nil
+	} else if dryRun {
+		if !jsonOutput && !quiet {
+			fmt.Printf("gorganize: would write %s (%d -> %d bytes)\n", path, len(input), len(output))
+		}
+		return true, nil
+	} else if showDiff {
+		return true, lo.Ternary(jsonOutput, nil, writeDiff(os.Stdout, path, input, output))
+	} else {
+		var perms os.FileMode
+		if fi, err := os.Stat(path); err == nil {
+			perms = fi.Mode() & os.ModePerm
+		}
+		if backup {
+			if err := os.WriteFile(path+".bak", input, perms); err != nil {
+				return false, err
+			}
+		}
+		if err := os.WriteFile(path, output, perms); err != nil {
+			return false, err
+		}
+		cache.add(config, path, output)
+		return true, nil
+	}
+}
+
+// Format the Go files named by args, or under the directories they name.
+// Like gofmt, files named directly are printed to standard output rather than overwritten, unless --write is set;
+// files found in directories are overwritten unless --no-write is set, which prints them too.
+// With --since, only the files git reports as changed since the ref are formatted, of those the paths cover.
+func formatFiles(ctx context.Context, args []string) error {
+	roots, err := resolvePaths(args)
+	if err != nil {
+		return err
+	}
+	var changed changedFiles // nil to format every file
+	if since != "" {
+		if changed, err = changedSince(since); err != nil {
 			return err
+		} else if changed == nil && !quiet {
+			fmt.Fprintf(
+				os.Stderr,
+				"gorganize: not in a git repository, so formatting all files rather than those changed since %s\n",
+				since,
+			)
+		}
+	}
+	rootPaths := lo.Map(roots, func(root walkRoot, _ int) string { return root.path })
+	return walkGoFiles(roots, func(path string) error {
+		if changed != nil && !changed.contains(path) {
+			return nil
+		} else if (noWrite || slices.Contains(rootPaths, path) && !write) && !reportOnly() {
+			summary.printFile(ctx, path)
 		} else {
-			paths = []string{abs}
+			summary.formatFile(ctx, path)
 		}
+		return ctx.Err()
+	})
+}
+
+// Format the files listed one per line in the file at listPath, or in standard input if listPath is "-".
+// Paths that aren't Go files are skipped with a warning.
+func formatFilesFrom(ctx context.Context, listPath string) error {
+	var list []byte
+	var err error
+	if listPath == "-" {
+		list, err = io.ReadAll(os.Stdin)
 	} else {
-		for _, arg := range args {
-			if abs, err := filepath.Abs(strings.ReplaceAll(arg, "...", "")); err != nil {
-				return err
-			} else {
-				paths = append(paths, abs)
+		list, err = os.ReadFile(listPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	for line := range strings.Lines(string(list)) {
+		if err := ctx.Err(); err != nil {
+			return err
+		} else if path := strings.TrimSpace(line); path == "" {
+			continue
+		} else if !isGoFile(path) {
+			if !quiet {
+				fmt.Fprintf(os.Stderr, "gorganize: skipping %s: not a Go file\n", path)
 			}
+		} else if noWrite && !reportOnly() {
+			summary.printFile(ctx, path)
+		} else {
+			summary.formatFile(ctx, path)
 		}
 	}
+	return nil
+}
 
-	for _, path := range paths {
-		if err := filepath.Walk(path, func(path string, f fs.FileInfo, err error) error {
-			if err != nil {
-				return err
-			} else if f.IsDir() || strings.HasPrefix(f.Name(), ".") || !strings.HasSuffix(f.Name(), ".go") {
-				return nil // not a Go file
-			}
+// Format src with config, giving up after --timeout if it's set.
+// With --verbose, the start and end are logged, so the file a run hangs on is the last one started.
+func formatSource(ctx context.Context, config formatters.Config, filename string, src []byte) ([]byte, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "gorganize: formatting %s\n", filename)
+	}
+	start := time.Now()
+	res, err := formatters.NewFormatterWithConfig(config).FormatResult(ctx, filename, src)
+	if panicErr := (*formatters.StagePanicError)(nil); debug && errors.As(err, &panicErr) {
+		panic(fmt.Sprintf("%s\n\n%s", err, panicErr.Stack))
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%s: timed out after %s", filename, timeout)
+	} else if stageErr := (*formatters.StageError)(nil); errors.As(err, &stageErr) {
+		return nil, saveStageInput(stageErr)
+	} else if err != nil {
+		return nil, err
+	}
 
-			var in *os.File
-			defer in.Close()
-			if in, err = os.Open(path); err != nil {
-				return err
-			} else if input, err := io.ReadAll(in); err != nil {
-				return err
-			} else if output, err := formatter.Format(path, input); err != nil {
-				return err
-			} else if bytes.Equal(input, output) {
-				return nil
-			} else {
-				var perms os.FileMode
-				if fi, err := os.Stat(path); err == nil {
-					perms = fi.Mode() & os.ModePerm
+	recordDeclOrder(filename, res.Formatted)
+	if verbose {
+		fmt.Fprintf(
+			os.Stderr,
+			"gorganize: formatted %s in %s, changed by: %s\n",
+			filename,
+			time.Since(start).Round(time.Microsecond),
+			lo.Ternary(len(res.Stages) > 0, strings.Join(res.Stages, ", "), "none"),
+		)
+	}
+	return res.Formatted, nil
+}
+
+// Format standard input to standard output.
+// With --check, the run fails if the input wasn't already formatted.
+func formatStdin(ctx context.Context) error {
+	if input, err := io.ReadAll(os.Stdin); err != nil {
+		return err
+	} else if config, err := fileConfig(stdinFilename); err != nil {
+		return err
+	} else if output, err := formatStdinSource(ctx, config, stdinFilename, input); err != nil {
+		return err
+	} else if showDiff {
+		return writeDiff(os.Stdout, stdinFilename, input, output)
+	} else if _, err = os.Stdout.Write(output); err != nil {
+		return err
+	} else if check && !bytes.Equal(input, output) {
+		return fmt.Errorf("%s is not formatted", stdinFilename)
+	}
+	return nil
+}
+
+// Format source read from standard input, or only the lines of it given with --range.
+func formatStdinSource(ctx context.Context, config formatters.Config, filename string, src []byte) ([]byte, error) {
+	if rangeLines == (formatters.LineRange{}) {
+		return formatSource(ctx, config, filename, src)
+	}
+	return formatters.NewFormatterWithConfig(config).FormatRange(filename, src, rangeLines)
+}
+
+// Report whether path names a Go file: one ending in .go or an extension given with --ext, and not hidden.
+func isGoFile(path string) bool {
+	name := filepath.Base(path)
+	return !strings.HasPrefix(name, ".") &&
+		slices.ContainsFunc(append([]string{".go"}, extensions...), func(ext string) bool {
+			return strings.HasSuffix(name, "."+strings.TrimPrefix(ext, "."))
+		})
+}
+
+func isNoColorFlag(arg string) bool {
+	return arg == "--no-color" || arg == "--no-color=true"
+}
+
+func isQuietFlag(arg string) bool {
+	return arg == "-q" || arg == "--quiet" || arg == "--quiet=true"
+}
+
+// Report whether path is a symlink to a directory.
+func isSymlinkToDir(path string) bool {
+	link, err := os.Lstat(path)
+	if err != nil || link.Mode()&fs.ModeSymlink == 0 {
+		return false
+	}
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+// Report whether path, relative to root, matches any of patterns.
+// A pattern without a slash is matched against the base name, so "*_gen.go" matches at any depth.
+func matchesAny(patterns []string, root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		name := lo.Ternary(strings.Contains(pattern, "/"), filepath.ToSlash(rel), filepath.Base(path))
+		matched, _ := doublestar.Match(pattern, name)
+		return matched
+	})
+}
+
+// Parse a --range value, two line numbers separated by a colon.
+func parseLineRange(value string) (formatters.LineRange, error) {
+	startText, endText, ok := strings.Cut(value, ":")
+	start, startErr := strconv.Atoi(startText)
+	end, endErr := strconv.Atoi(endText)
+	if !ok || startErr != nil || endErr != nil {
+		return formatters.LineRange{}, fmt.Errorf("invalid range %q: must be start:end, as line numbers", value)
+	}
+	return formatters.LineRange{Start: start, End: end}, nil
+}
+
+// Format the Go file at path to standard output, leaving the file as is.
+// With --json, the output is only compared to the file, not printed.
+func printFile(ctx context.Context, path string) (changed bool, err error) {
+	input, release, err := readFile(path)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	if config, err := fileConfig(path); err != nil {
+		return false, err
+	} else if output, err := formatSource(ctx, config, path, input); err != nil {
+		return false, err
+	} else if jsonOutput {
+		return !bytes.Equal(input, output), nil
+	} else {
+		_, err := os.Stdout.Write(output)
+		return !bytes.Equal(input, output), err
+	}
+}
+
+// Read the file at path into a buffer from a pool, so a run over many large files reuses a few buffers
+// rather than allocating one for each. Call release once done with the contents to return the buffer to the pool.
+func readFile(path string) (contents []byte, release func(), err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	buf := readBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	if fi, err := f.Stat(); err == nil {
+		buf.Grow(int(fi.Size()) + bytes.MinRead) // room to read EOF without growing again
+	}
+	if _, err := buf.ReadFrom(f); err != nil {
+		readBuffers.Put(buf)
+		return nil, nil, err
+	}
+	return buf.Bytes(), func() { readBuffers.Put(buf) }, nil
+}
+
+// Report whether a flag is set that reports on files rather than writing or printing them.
+func reportOnly() bool {
+	return check || dryRun || showDiff
+}
+
+// Apply the flags that aren't bound directly to config, and validate the result.
+// Shared by the commands that read the formatting flags.
+func resolveConfig(cmd *cobra.Command) error {
+	for _, stage := range formatters.DefaultStages() {
+		if skip, _ := cmd.Flags().GetBool("no-" + stage); skip {
+			config.DisabledStages = append(config.DisabledStages, stage)
+		}
+	}
+	if sortOnly, _ := cmd.Flags().GetBool("sort-only"); sortOnly {
+		config.Stages = []string{"aifi"} // so a review diff shows only what moved
+		config.MinimalDiff = true
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	for _, pattern := range slices.Concat(excludes, includes) {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid glob pattern %q", pattern)
+		}
+	}
+	cmd.SilenceUsage = true // flags are valid, so further errors are about the input
+	maxLenSet = cmd.Flags().Changed("max-len")
+	return nil
+}
+
+// Resolve path arguments to the roots to walk, defaulting to the current directory.
+// A "..." suffix is dropped, since directories are walked recursively unless --no-recursive is set.
+// An argument that doesn't exist on disk is tried as a Go package pattern, like github.com/me/pkg/...,
+// standing for the directories of the packages it matches; see packageDirs. As in the go command,
+// a pattern without "..." stands for the packages themselves, so only the files directly in their directories count.
+func resolvePaths(args []string) ([]walkRoot, error) {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	var roots []walkRoot
+	for _, arg := range args {
+		path := strings.ReplaceAll(arg, "...", "")
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			if dirs, err := packageDirs(arg); err == nil && len(dirs) > 0 {
+				for _, dir := range dirs {
+					roots = append(roots, walkRoot{path: dir, flat: !strings.Contains(arg, "...")})
 				}
-				return os.WriteFile(path, output, perms)
+				continue
+			}
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		} else if isSymlinkToDir(abs) {
+			abs, err = filepath.EvalSymlinks(abs) // filepath.Walk doesn't follow a root that's a symlink
+			if err != nil {
+				return nil, err
 			}
-		}); err != nil {
-			return err
 		}
+		roots = append(roots, walkRoot{path: abs})
 	}
-	return nil
+	return roots, nil
 }
 
-func formatStdin() error {
-	if bytes, err := io.ReadAll(os.Stdin); err != nil {
+func run(cmd *cobra.Command, args []string) error {
+	if noColor {
+		color.NoColor = true
+	}
+	if value, _ := cmd.Flags().GetString("range"); value != "" {
+		if !stdin {
+			return errors.New("--range requires --stdin")
+		}
+		var err error
+		if rangeLines, err = parseLineRange(value); err != nil {
+			return err
+		}
+	}
+	if err := resolveConfig(cmd); err != nil {
 		return err
-	} else if bytes, err = formatter.Format("<standard input>", bytes); err != nil {
+	}
+	write = write || preCommit // pre-commit passes the files to format as arguments
+	if !cmd.Flags().Changed("github") {
+		githubOutput = os.Getenv("GITHUB_ACTIONS") == "true"
+	}
+
+	if useCache, _ := cmd.Flags().GetBool("cache"); useCache {
+		var err error
+		if cache, err = newFormatCache(); err != nil {
+			return fmt.Errorf("opening cache: %w", err)
+		}
+	}
+	if stopProfiles, err := startProfiles(); err != nil {
 		return err
 	} else {
-		_, err = os.Stdout.Write(bytes)
+		defer stopProfiles()
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+	var err error
+	if stdin && jsonOutput {
+		return formatStdinJSON(ctx)
+	} else if stdin {
+		return formatStdin(ctx)
+	} else if watchMode {
+		return watch(ctx, args)
+	} else if filesFrom != "" {
+		if len(args) > 0 {
+			return errors.New("--files-from can't be combined with path arguments")
+		}
+		err = formatFilesFrom(ctx, filesFrom)
+	} else if staged {
+		if len(args) > 0 {
+			return errors.New("--staged can't be combined with path arguments")
+		}
+		err = formatStaged(ctx)
+	} else {
+		err = formatFiles(ctx, args)
+	}
+	if errors.Is(err, context.Canceled) {
+		err = errors.New("interrupted")
+	} else if checkVariants {
+		err = cmp.Or(err, checkDeclOrders())
+	}
+
+	if jsonOutput {
+		err = cmp.Or(err, summary.writeJSON(os.Stdout))
+	} else if showSummary {
+		fmt.Println(summary)
+	}
+	return cmp.Or(err, summary.err())
+}
+
+// Save the source a stage failed on to a temporary file, and return err with its path to reproduce the failure with.
+func saveStageInput(err *formatters.StageError) error {
+	f, createErr := os.CreateTemp("", "gorganize-"+err.Stage+"-input-*.go")
+	if createErr != nil {
 		return err
 	}
+	defer f.Close()
+	if _, writeErr := f.Write(err.Input); writeErr != nil {
+		return err
+	}
+	return fmt.Errorf("%w; its input is saved in %s", err, f.Name())
 }
 
-func run(_ *cobra.Command, args []string) error {
-	if stdin {
-		return formatStdin()
+// Report whether walkGoFiles skips path under root, a directory if isDir is set,
+// given the ignore rules of the directory it's in. Skipping a directory skips everything in it.
+func skipPath(root walkRoot, path string, isDir bool, rules ignoreRules) bool {
+	depth := lo.Ternary(noRecursive || root.flat, 0, maxDepth)
+	if path == root.path {
+		return false
+	} else if isDir && filepath.Base(path) == "testdata" {
+		return true // fixtures, ignored like the go tool does
+	} else if isDir && depth >= 0 && dirDepth(root.path, path) > depth {
+		return true
+	} else if matchesAny(excludes, root.path, path) || rules.ignored(path, isDir) {
+		return true
+	} else if isDir {
+		return false
 	}
-	return formatFiles(args)
+	return !isGoFile(path) || len(includes) > 0 && !matchesAny(includes, root.path, path)
+}
+
+// Call fn with the path of each Go file under roots, down to --max-depth levels below them.
+// --no-recursive is the same as a depth of 0, covering only the files directly in each root, as is a root that's
+// a single package.
+// Files and directories matching --exclude or a .gorganizeignore file are skipped,
+// as are files not matching --include if it's set; roots given explicitly are never filtered.
+// Only the .gorganizeignore files in the walked directories count, not those above a root.
+func walkGoFiles(roots []walkRoot, fn func(path string) error) error {
+	for _, root := range roots {
+		ignores := map[string]ignoreRules{} // by directory
+		if err := filepath.Walk(root.path, func(path string, f fs.FileInfo, err error) error {
+			rules := ignores[filepath.Dir(path)]
+			if err != nil {
+				return err
+			} else if skipPath(root, path, f.IsDir(), rules) {
+				return lo.Ternary(f.IsDir(), filepath.SkipDir, nil)
+			} else if f.IsDir() {
+				own, err := readIgnoreFile(path)
+				ignores[filepath.Clean(path)] = slices.Concat(rules, own)
+				return err
+			}
+			return fn(path)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/autumnkelsey/gorganize/formatters"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+const (
+	formattedSource   = "package foo\n\nfunc a() {}\n\nfunc b() {}\n"
+	unformattedSource = "package foo\nfunc  b() {}\nfunc a() {}\n"
+)
+
+// Formatting a module rewrites the files of every package the arguments stand for, and leaves the rest alone.
+func TestFormatFilesModule(t *testing.T) {
+	files := map[string]string{
+		"go.mod":                                 "module example.com/mod\n\ngo 1.24\n",
+		"a.go":                                   unformattedSource,
+		filepath.Join("pkg", "b.go"):             unformattedSource,
+		filepath.Join("pkg", "sub", "c.go"):      unformattedSource,
+		filepath.Join("pkg", "testdata", "d.go"): unformattedSource,
+	}
+	cases := map[string][]string{
+		"./...":                   {"a.go", filepath.Join("pkg", "b.go"), filepath.Join("pkg", "sub", "c.go")},
+		"./pkg/...":               {filepath.Join("pkg", "b.go"), filepath.Join("pkg", "sub", "c.go")},
+		"example.com/mod/pkg":     {filepath.Join("pkg", "b.go")},
+		"example.com/mod/pkg/...": {filepath.Join("pkg", "b.go"), filepath.Join("pkg", "sub", "c.go")},
+	}
+	for arg, formatted := range cases {
+		t.Run(arg, func(t *testing.T) {
+			setGlobal(t, &summary, runSummary{})
+			dir := t.TempDir()
+			writeFiles(t, dir, files)
+			t.Chdir(dir)
+
+			if out := captureStdout(t, func() {
+				if err := formatFiles(context.Background(), []string{arg}); err != nil {
+					t.Error(err)
+				}
+			}); out != "" {
+				t.Errorf("printed %q, want nothing", out)
+			}
+
+			for name, content := range files {
+				want := lo.Ternary(slices.Contains(formatted, name), formattedSource, content)
+				if got, err := os.ReadFile(filepath.Join(dir, name)); err != nil {
+					t.Fatal(err)
+				} else if string(got) != want {
+					t.Errorf("%s contains %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// Like gofmt, files named directly are printed unless --write is set, and files found in directories are written
+// unless --no-write is set.
+func TestFormatFilesWrite(t *testing.T) {
+	cases := map[string]struct {
+		dirArg           bool
+		write, noWrite   bool
+		printed, written bool
+	}{
+		"file":                      {printed: true},
+		"directory":                 {dirArg: true, written: true},
+		"file with -w":              {write: true, written: true},
+		"directory with --no-write": {dirArg: true, noWrite: true, printed: true},
+		"file with --no-write":      {noWrite: true, printed: true},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			setGlobal(t, &write, c.write)
+			setGlobal(t, &noWrite, c.noWrite)
+			setGlobal(t, &summary, runSummary{})
+			dir := t.TempDir()
+			path := filepath.Join(dir, "foo.go")
+			writeFiles(t, dir, map[string]string{"foo.go": unformattedSource})
+
+			arg := path
+			if c.dirArg {
+				arg = dir
+			}
+			var err error
+			out := captureStdout(t, func() { err = formatFiles(context.Background(), []string{arg}) })
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := lo.Ternary(c.printed, formattedSource, ""); out != want {
+				t.Errorf("printed %q, want %q", out, want)
+			}
+			want := lo.Ternary(c.written, formattedSource, unformattedSource)
+			if got, err := os.ReadFile(path); err != nil {
+				t.Fatal(err)
+			} else if string(got) != want {
+				t.Errorf("file contains %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// --sort-only moves declarations into order and changes nothing else: long lines aren't split, and declarations
+// that stay next to each other keep the blank lines between them.
+func TestSortOnly(t *testing.T) {
+	const (
+		long  = "func a() { println(\"a line well past the default maximum length, which golines would otherwise split\", 1, 2, 3) }\n"
+		b     = "func b() {\n\n\tprintln()\n}\n"
+		c     = "func c() {}\n"
+		input = "package foo\n\n" + c + "\n" + long + "\n\n\n" + b
+		want  = "package foo\n\n" + long + "\n\n\n" + b + "\n" + c
+	)
+	setGlobal(t, &config, formatters.DefaultConfig())
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("sort-only", false, "")
+	if err := cmd.Flags().Set("sort-only", "true"); err != nil {
+		t.Fatal(err)
+	} else if err := resolveConfig(cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := formatSource(context.Background(), config, "foo.go", []byte(input))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// Call fn and return what it wrote to standard output.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+// Set the global at ptr to value for the rest of the test.
+func setGlobal[T any](t *testing.T, ptr *T, value T) {
+	t.Helper()
+	old := *ptr
+	t.Cleanup(func() { *ptr = old })
+	*ptr = value
+}
+
+// Write files, keyed by their paths relative to dir, creating directories as needed.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
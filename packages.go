@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Resolve a Go package pattern, like github.com/me/pkg/..., to the directories of the packages it matches, using go list.
+// Only packages of the main module or workspace are included, so the standard library and dependencies are never rewritten.
+// For a pattern with "...", directories inside another one are left out, since directories are walked recursively.
+func packageDirs(pattern string) ([]string, error) {
+	out, err := exec.Command("go", "list", "-f", "{{with .Module}}{{if .Main}}{{$.Dir}}{{end}}{{end}}", pattern).
+		Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, dir := range slices.Sorted(strings.Lines(strings.TrimSpace(string(out)))) {
+		dir = strings.TrimSpace(dir)
+		if dir != "" && !(strings.Contains(pattern, "...") && slices.ContainsFunc(dirs, func(parent string) bool {
+			return strings.HasPrefix(dir, parent+string(filepath.Separator))
+		})) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// A package path names just that package, so its subpackages are only walked for a pattern with "...".
+func TestResolvePathsNestedPackages(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":                            "module example.com/nest\n\ngo 1.24\n",
+		filepath.Join("pkg", "a.go"):        "package pkg\n",
+		filepath.Join("pkg", "sub", "b.go"): "package sub\n",
+	})
+	t.Chdir(dir)
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string][]string{
+		"example.com/nest/pkg":     {filepath.Join("pkg", "a.go")},
+		"example.com/nest/pkg/...": {filepath.Join("pkg", "a.go"), filepath.Join("pkg", "sub", "b.go")},
+		"./pkg":                    {filepath.Join("pkg", "a.go"), filepath.Join("pkg", "sub", "b.go")},
+	}
+	for pattern, want := range cases {
+		t.Run(pattern, func(t *testing.T) {
+			roots, err := resolvePaths([]string{pattern})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			if err := walkGoFiles(roots, func(path string) error {
+				rel, err := filepath.Rel(dir, path)
+				got = append(got, rel)
+				return err
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("walked %q, want %q", got, want)
+			}
+		})
+	}
+}
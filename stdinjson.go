@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// The object read from standard input with --stdin --json, e.g. {"filename": "foo/bar.go", "source": "package bar\n"}.
+// Filename is optional and defaults to --stdin-filename; it's used for .editorconfig, import grouping, and errors.
+type stdinRequest struct {
+	Filename string  `json:"filename"`
+	Source   *string `json:"source"` // required, so that a misspelled field isn't taken for an empty file
+}
+
+// The object written to standard output with --stdin --json.
+// Exactly one of the fields is set: Formatted on success, even if it's empty, or Error if the request failed,
+// including when it isn't valid JSON. The run fails in both cases, as it does without --json.
+type stdinResponse struct {
+	Error     string  `json:"error,omitempty"`
+	Formatted *string `json:"formatted,omitempty"`
+}
+
+// Format the source in the JSON request on standard input and write a JSON response to standard output.
+// Errors are reported in the response as well as by the returned error, so a client only needs to parse stdout.
+func formatStdinJSON(ctx context.Context) error {
+	formatted, err := formatStdinRequest(ctx)
+	res := stdinResponse{Formatted: formatted}
+	if err != nil {
+		res = stdinResponse{Error: err.Error()}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false) // error messages name files like <standard input>
+	if encodeErr := enc.Encode(res); encodeErr != nil {
+		return encodeErr
+	}
+	return err
+}
+
+// Decode the request on standard input and return its formatted source.
+func formatStdinRequest(ctx context.Context) (*string, error) {
+	var req stdinRequest
+	dec := json.NewDecoder(os.Stdin)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid JSON request on standard input: %w", err)
+	} else if dec.More() {
+		return nil, errors.New("invalid JSON request on standard input: more than one object")
+	} else if req.Source == nil {
+		return nil, errors.New(`invalid JSON request on standard input: missing "source"`)
+	}
+
+	filename := req.Filename
+	if filename == "" {
+		filename = stdinFilename
+	}
+	src := []byte(*req.Source)
+	if config, err := fileConfig(filename); err != nil {
+		return nil, err
+	} else if output, err := formatStdinSource(ctx, config, filename, src); err != nil {
+		return nil, err
+	} else if check && !bytes.Equal(src, output) {
+		return nil, fmt.Errorf("%s is not formatted", filename)
+	} else {
+		formatted := string(output)
+		return &formatted, nil
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The outcome of formatting one file, as reported by --json.
+type fileResult struct {
+	Changed bool   `json:"changed"`
+	Error   string `json:"error,omitempty"`
+	Path    string `json:"path"`
+}
+
+// The outcome of a run over many files.
+type runSummary struct {
+	failed      int
+	reformatted int
+	results     []fileResult // only with --json
+	scanned     int
+}
+
+// A single machine-parseable line, e.g. "gorganize: 320 files scanned, 12 reformatted, 2 failed".
+func (s runSummary) String() string {
+	return fmt.Sprintf("gorganize: %d files scanned, %d reformatted, %d failed", s.scanned, s.reformatted, s.failed)
+}
+
+// An error if any file failed to format, or with --check or --pre-commit needs formatting,
+// so that the run exits non-zero.
+func (s runSummary) err() error {
+	if s.failed > 0 {
+		return fmt.Errorf("%d of %d files failed to format", s.failed, s.scanned)
+	} else if check && s.reformatted > 0 {
+		return fmt.Errorf("%d of %d files need formatting", s.reformatted, s.scanned)
+	} else if preCommit && s.reformatted > 0 {
+		return fmt.Errorf("%d of %d files were reformatted; review and stage them", s.reformatted, s.scanned)
+	}
+	return nil
+}
+
+// Format the Go file at path, count the outcome, and report whether the file changed.
+// A failure is reported right away and counted rather than stopping the run.
+func (s *runSummary) formatFile(ctx context.Context, path string) (changed bool) {
+	changed, err := formatFile(ctx, path)
+	s.record(path, changed, err)
+	return changed
+}
+
+// Format the Go file at path to standard output, and count the outcome like formatFile.
+func (s *runSummary) printFile(ctx context.Context, path string) {
+	changed, err := printFile(ctx, path)
+	s.record(path, changed, err)
+}
+
+// Count the outcome of formatting the file at path, and report it right away unless it's collected for --json.
+// With --check or --pre-commit, files that changed are listed, as annotations with --github;
+// with --quiet, only failures are reported.
+func (s *runSummary) record(path string, changed bool, err error) {
+	s.scanned++
+	if err != nil {
+		s.failed++
+	} else if changed {
+		s.reformatted++
+	}
+
+	if jsonOutput {
+		result := fileResult{Changed: changed, Path: path}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		s.results = append(s.results, result)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "gorganize: %s\n", err)
+	} else if check && changed && !quiet && githubOutput {
+		fmt.Printf("::error file=%s::needs formatting\n", escapeGitHubProperty(gitHubPath(path)))
+	} else if (check || preCommit) && changed && !quiet {
+		fmt.Println(path)
+	}
+}
+
+// Write the results of each file as a JSON array.
+func (s runSummary) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(append(make([]fileResult, 0, len(s.results)), s.results...)) // [] rather than null when empty
+}
+
+// Escape a property value of a GitHub Actions workflow command, like the file of an annotation.
+func escapeGitHubProperty(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(value)
+}
+
+// Return path relative to the workspace GitHub Actions checks the repository out in, or the current directory
+// outside it, since GitHub only shows annotations on the lines of a change for paths relative to the repository.
+// Paths outside it are returned as they are.
+func gitHubPath(path string) string {
+	base := os.Getenv("GITHUB_WORKSPACE")
+	if base == "" {
+		base, _ = os.Getwd()
+	}
+	rel, err := filepath.Rel(base, path)
+	if base == "" || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGitHubPath(t *testing.T) {
+	workspace := t.TempDir()
+	cases := map[string]struct{ workspace, path, want string }{
+		"in workspace": {workspace, filepath.Join(workspace, "pkg", "a.go"), "pkg/a.go"},
+		"outside workspace": {
+			workspace,
+			filepath.Join(filepath.Dir(workspace), "a.go"),
+			filepath.Join(filepath.Dir(workspace), "a.go"),
+		},
+		"current directory": {"", filepath.Join(workspace, "b.go"), "b.go"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("GITHUB_WORKSPACE", c.workspace)
+			t.Chdir(workspace)
+			if got := gitHubPath(c.path); got != c.want {
+				t.Errorf("gitHubPath(%q) = %q, want %q", c.path, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// Build variant suffixes of file names, as in foo_linux.go or foo_windows_arm64.go, from the go tool's lists.
+// "unix" isn't one the go tool applies, but it's often used with a matching //go:build line.
+var (
+	knownArches = []string{
+		"386", "amd64", "amd64p32", "arm", "arm64", "arm64be", "armbe", "loong64", "mips", "mips64", "mips64le",
+		"mips64p32", "mips64p32le", "mipsle", "ppc", "ppc64", "ppc64le", "riscv", "riscv64", "s390", "s390x",
+		"sparc", "sparc64", "wasm",
+	}
+	knownOSes = []string{
+		"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js", "linux", "nacl",
+		"netbsd", "openbsd", "plan9", "solaris", "unix", "wasip1", "windows", "zos",
+	}
+)
+
+// Declaration names of each file formatted with --check-variants, in order.
+var declOrders = map[string][]string{}
+
+// Report build variants of the same file, like foo_linux.go and foo_windows.go, that declare the same names
+// in a different order. Only the names both files declare are compared, since variants often differ in what they declare.
+// With --strict, any mismatch fails the run.
+func checkDeclOrders() error {
+	groups := lo.GroupBy(slices.Sorted(maps.Keys(declOrders)), variantKey)
+	delete(groups, "")
+
+	mismatches := 0
+	for _, key := range slices.Sorted(maps.Keys(groups)) {
+		paths := groups[key]
+		for _, path := range paths[1:] {
+			first, other := commonOrder(
+				declOrders[paths[0]],
+				declOrders[path],
+			), commonOrder(
+				declOrders[path],
+				declOrders[paths[0]],
+			)
+			if !slices.Equal(first, other) {
+				mismatches++
+				if !quiet {
+					fmt.Fprintf(os.Stderr, "gorganize: %s and %s declare %s in a different order\n",
+						paths[0], path, strings.Join(first, ", "))
+				}
+			}
+		}
+	}
+	if strict && mismatches > 0 {
+		return fmt.Errorf("%d build variants declare names in a different order", mismatches)
+	}
+	return nil
+}
+
+// Return the names in order that are also in other.
+func commonOrder(order, other []string) []string {
+	return lo.Filter(order, func(name string, _ int) bool { return slices.Contains(other, name) })
+}
+
+// Return the name of a method's receiver type, without any pointer, type parameters, or parentheses.
+func receiverTypeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.IndexExpr:
+		return receiverTypeName(expr.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(expr.X)
+	case *ast.ParenExpr:
+		return receiverTypeName(expr.X)
+	case *ast.StarExpr:
+		return receiverTypeName(expr.X)
+	}
+	return types.ExprString(expr)
+}
+
+// With --check-variants, record the order of the top-level declarations in src, the formatted source of the file at path,
+// for checkDeclOrders. Methods are named like T.M. Source that doesn't parse is skipped.
+func recordDeclOrder(path string, src []byte) {
+	if !checkVariants {
+		return
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), path, src, parser.SkipObjectResolution)
+	if err != nil {
+		return
+	}
+
+	var names []string
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				names = append(names, receiverTypeName(decl.Recv.List[0].Type)+"."+decl.Name.Name)
+			} else {
+				names = append(names, decl.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, spec.Name.Name)
+				case *ast.ValueSpec:
+					names = append(names, lo.Map(spec.Names, func(name *ast.Ident, _ int) string { return name.Name })...)
+				}
+			}
+		}
+	}
+	declOrders[path] = slices.DeleteFunc(names, func(name string) bool { return name == "_" || name == "init" })
+}
+
+// Return the path of the file that path is a build variant of, without its GOOS and GOARCH suffixes,
+// or "" if it has none. A test file's variants are other test files.
+func variantKey(path string) string {
+	stem, isTest := strings.CutSuffix(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), "_test")
+	parts := strings.Split(stem, "_")
+	n := len(parts)
+	if n > 1 && slices.Contains(knownArches, parts[n-1]) {
+		n--
+	}
+	if n > 1 && slices.Contains(knownOSes, parts[n-1]) {
+		n--
+	}
+	if n == len(parts) {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(path), strings.Join(parts[:n], "_")+lo.Ternary(isTest, "_test", ""))
+}
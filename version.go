@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	runtimedebug "runtime/debug"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+// Set at build time, e.g. -ldflags "-X main.version=v1.2.3 -X main.commit=abc1234".
+// When unset, they fall back to the module version and VCS revision recorded in the build info.
+var (
+	commit  string
+	version string
+)
+
+// modules whose versions affect formatting output, reported by the version command
+var formatterModules = []string{
+	"github.com/daixiang0/gci",
+	"github.com/golangci/golines",
+}
+
+func newVersionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print gorganize, Go, and formatter versions",
+		Args:  cobra.NoArgs,
+		RunE:  printVersion,
+	}
+}
+
+func printVersion(cmd *cobra.Command, _ []string) error {
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "gorganize %s\n", versionString())
+	if info, ok := runtimedebug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if slices.Contains(formatterModules, dep.Path) {
+				fmt.Fprintf(out, "%s %s\n", dep.Path, dep.Version)
+			}
+		}
+	}
+	return nil
+}
+
+// The version, commit, and Go version gorganize was built with, e.g. "v1.2.3 (commit abc1234, built with go1.24.7)".
+func versionString() string {
+	v, c := version, commit
+	if info, ok := runtimedebug.ReadBuildInfo(); ok {
+		if v == "" {
+			v = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			if c == "" && setting.Key == "vcs.revision" {
+				c = setting.Value
+			}
+		}
+	}
+	if v == "" {
+		v = "(devel)"
+	}
+	if c == "" {
+		c = "unknown"
+	}
+	return fmt.Sprintf("%s (commit %s, built with %s)", v, c, runtime.Version())
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintVersion(t *testing.T) {
+	var out bytes.Buffer
+	cmd := newVersionCommand()
+	cmd.SetOut(&out)
+	cmd.SetArgs(nil)
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if version, ok := strings.CutPrefix(lines[0], "gorganize "); !ok || !strings.Contains(version, "built with go") {
+		t.Errorf("got first line %q, want the gorganize and Go versions", lines[0])
+	}
+	for _, module := range formatterModules {
+		found := false
+		for _, line := range lines[1:] {
+			if version, ok := strings.CutPrefix(line, module+" "); ok && version != "" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("no version of %s in:\n%s", module, out.String())
+		}
+	}
+}
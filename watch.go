@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// How long a file must go without further writes before it's formatted,
+// so that an editor saving in several steps triggers a single run.
+const watchDebounce = 100 * time.Millisecond
+
+// Format the Go files under the path arguments whenever they change, until ctx is done.
+// The directories holding the Go files a normal run would format are watched, along with the roots themselves.
+//
+// A changed file is formatted only if a normal run would format it, with --exclude, --include,
+// and .gorganizeignore files applied the same way.
+//
+// Writes made by gorganize are recognized by the modification time it leaves on the file and ignored,
+// so formatting a file doesn't trigger another run on it.
+func watch(ctx context.Context, args []string) error {
+	roots, err := resolvePaths(args)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	dirs := map[string]bool{}
+	for _, root := range roots {
+		if fi, err := os.Stat(root.path); err == nil && fi.IsDir() {
+			dirs[root.path] = true
+		}
+	}
+	if err := walkGoFiles(roots, func(path string) error {
+		dirs[filepath.Dir(path)] = true
+		return nil
+	}); err != nil {
+		return err
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+	}
+
+	ownWrites := map[string]time.Time{} // modification times of the files gorganize wrote
+	pending := map[string]*time.Timer{} // files waiting out the debounce
+	ready := make(chan string)          // files done debouncing
+	if !quiet {
+		fmt.Printf("gorganize: watching %d directories\n", len(dirs))
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			} else if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			} else if selected, err := watchedGoFile(roots, event.Name); err != nil {
+				fmt.Fprintf(os.Stderr, "gorganize: %s\n", err)
+				continue
+			} else if !selected {
+				continue
+			} else if timer, ok := pending[event.Name]; ok {
+				timer.Reset(watchDebounce)
+			} else {
+				pending[event.Name] = time.AfterFunc(watchDebounce, func() { ready <- event.Name })
+			}
+		case path := <-ready:
+			delete(pending, path)
+			fi, err := os.Stat(path)
+			if err != nil || fi.ModTime().Equal(ownWrites[path]) {
+				continue // removed since, or our own write
+			} else if changed, err := formatFile(ctx, path); err != nil {
+				fmt.Fprintf(os.Stderr, "gorganize: %s\n", err)
+			} else if changed {
+				if fi, err := os.Stat(path); err == nil {
+					ownWrites[path] = fi.ModTime()
+				}
+				if !quiet {
+					fmt.Printf("gorganize: formatted %s\n", path)
+				}
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Report whether walkGoFiles would call its function with path, a file under roots, applying the same filters.
+// The .gorganizeignore files between the root and path are read again each time, so edits to them take effect.
+func watchedGoFile(roots []walkRoot, path string) (bool, error) {
+	for _, root := range roots {
+		rel, err := filepath.Rel(root.path, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		} else if rel == "." {
+			return true, nil // named directly
+		}
+
+		rules, err := readIgnoreFile(root.path)
+		if err != nil {
+			return false, err
+		}
+		dir, skipped := root.path, false
+		for _, name := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+			if name == "." {
+				break // path is directly in root
+			}
+			dir = filepath.Join(dir, name)
+			if skipped = skipPath(root, dir, true, rules); skipped {
+				break
+			}
+			own, err := readIgnoreFile(dir)
+			if err != nil {
+				return false, err
+			}
+			rules = slices.Concat(rules, own)
+		}
+		if !skipped && !skipPath(root, path, false, rules) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Watch mode formats a changed file only if a normal run over the same roots would.
+func TestWatchedGoFile(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		ignoreFileName:                               "skipped.go\nignored/\n",
+		"a.go":                                       "",
+		"skipped.go":                                 "",
+		"x_gen.go":                                   "",
+		"notes.txt":                                  "",
+		filepath.Join("ignored", "b.go"):             "",
+		filepath.Join("sub", ignoreFileName):         "c.go\n",
+		filepath.Join("sub", "c.go"):                 "",
+		filepath.Join("sub", "d.go"):                 "",
+		filepath.Join("sub", "deep", "e.go"):         "",
+		filepath.Join("sub", "testdata", "input.go"): "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[string]struct {
+		excludes, includes []string
+		maxDepth           int
+		want               []string
+	}{
+		"defaults": {nil, nil, -1, []string{"a.go", "x_gen.go", "sub/d.go", "sub/deep/e.go"}},
+		"exclude":  {[]string{"*_gen.go", "sub/deep"}, nil, -1, []string{"a.go", "sub/d.go"}},
+		"include":  {nil, []string{"sub/**"}, -1, []string{"sub/d.go", "sub/deep/e.go"}},
+		"depth":    {nil, nil, 1, []string{"a.go", "x_gen.go", "sub/d.go"}},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			oldExcludes, oldIncludes, oldMaxDepth := excludes, includes, maxDepth
+			t.Cleanup(func() { excludes, includes, maxDepth = oldExcludes, oldIncludes, oldMaxDepth })
+			excludes, includes, maxDepth = c.excludes, c.includes, c.maxDepth
+
+			var walked []string
+			if err := walkGoFiles([]walkRoot{{path: root}}, func(path string) error {
+				walked = append(walked, path)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			want := make([]string, len(c.want))
+			for i, name := range c.want {
+				want[i] = filepath.Join(root, filepath.FromSlash(name))
+			}
+			if slices.Sort(walked); !slices.Equal(walked, slices.Sorted(slices.Values(want))) {
+				t.Errorf("walked %v, want %v", walked, want)
+			}
+
+			for name := range files {
+				path := filepath.Join(root, name)
+				watched, err := watchedGoFile([]walkRoot{{path: root}}, path)
+				if err != nil {
+					t.Fatal(err)
+				} else if watched != slices.Contains(want, path) {
+					t.Errorf("watchedGoFile(%s) = %t, want %t", name, watched, !watched)
+				}
+			}
+		})
+	}
+}
+
+// A file named directly is formatted whatever the filters say, and files outside the roots never are.
+func TestWatchedGoFileRoots(t *testing.T) {
+	oldExcludes := excludes
+	t.Cleanup(func() { excludes = oldExcludes })
+	excludes = []string{"*_gen.go"}
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "x_gen.go")
+	for path, want := range map[string]bool{root: true, filepath.Join(dir, "other.go"): false} {
+		if watched, err := watchedGoFile([]walkRoot{{path: root}}, path); err != nil {
+			t.Fatal(err)
+		} else if watched != want {
+			t.Errorf("watchedGoFile(%s) = %t, want %t", path, watched, want)
+		}
+	}
+}